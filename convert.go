@@ -0,0 +1,90 @@
+package main
+
+import (
+	"debug/elf"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/tautologico/amginspect/hunk"
+)
+
+// convert implements the "amginspect convert" subcommand, a native Go
+// replacement for the amigaos-cross-toolchain's elf2hunk and
+// hunk2aout tools.
+func convert(args []string) {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	from := fs.String("from", "", "source format: elf or hunk")
+	to := fs.String("to", "", "target format: hunk or aout")
+	chipFlag := fs.String("chip", "", "comma-separated ELF section names to allocate from chip memory")
+	fs.Parse(args)
+
+	rest := fs.Args()
+	if len(rest) != 2 {
+		fmt.Println("usage: amginspect convert --from <elf|hunk> --to <hunk|aout> [--chip=.text,.data] <in> <out>")
+		os.Exit(1)
+	}
+	in, out := rest[0], rest[1]
+
+	switch {
+	case *from == "elf" && *to == "hunk":
+		elf2hunk(in, out, *chipFlag)
+	case *from == "hunk" && *to == "aout":
+		hunk2aout(in, out)
+	default:
+		log.Fatalf("unsupported conversion: --from %s --to %s", *from, *to)
+	}
+}
+
+// elf2hunk converts an m68k ELF object file into an AmigaOS Hunk
+// executable, marking the sections named in chipFlag as chip memory.
+func elf2hunk(in, out, chipFlag string) {
+	ef, err := elf.Open(in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer ef.Close()
+
+	chip := make(map[string]bool)
+	for _, name := range strings.Split(chipFlag, ",") {
+		if name != "" {
+			chip[name] = true
+		}
+	}
+
+	hf, err := hunk.FromELF(ef, chip)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w, err := os.Create(out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer w.Close()
+
+	if _, err := hf.WriteTo(w); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// hunk2aout converts an AmigaOS Hunk file into a minimal OMAGIC a.out
+// image.
+func hunk2aout(in, out string) {
+	hf, err := hunk.Open(in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer hf.Close()
+
+	data, err := hunk.ToAOUT(hf)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile(out, data, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,110 @@
+package hunk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// Classic 32-bit a.out header magic numbers and nlist type bits, as
+// used by the amigaos-cross-toolchain's hunk2aout.
+const (
+	aoutOMAGIC = 0x00000107
+
+	aoutNText = 0x04
+	aoutNData = 0x06
+	aoutNBSS  = 0x08
+	aoutNExt  = 0x01
+)
+
+// aoutHeaderSize is the size in bytes of the classic a.out exec
+// header: magic, text/data/bss sizes, symbol table size, entry point,
+// text and data relocation table sizes.
+const aoutHeaderSize = 32
+
+// ToAOUT converts a Hunk File into a minimal OMAGIC a.out image: every
+// HUNK_CODE hunk is concatenated into one text segment, every
+// HUNK_DATA hunk into one data segment, and every HUNK_BSS hunk
+// contributes to a single merged bss size. HUNK_SYMBOL entries are
+// carried over into the a.out symbol table, with their values rebased
+// onto the merged segments. No relocations are emitted, and the
+// hunks' own relocations are not applied: code and data bytes are
+// copied exactly as stored in f.
+func ToAOUT(f *File) ([]byte, error) {
+	var text, data bytes.Buffer
+	var bssSize uint32
+
+	textBase := make([]uint32, len(f.Hunks))
+	dataBase := make([]uint32, len(f.Hunks))
+	bssBase := make([]uint32, len(f.Hunks))
+
+	for i, h := range f.Hunks {
+		buf := make([]byte, h.Size)
+		if h.Size > 0 && h.Type != HunkBSS {
+			if _, err := io.ReadFull(h.Open(), buf); err != nil {
+				return nil, err
+			}
+		}
+
+		switch h.Type {
+		case HunkCode:
+			textBase[i] = uint32(text.Len())
+			text.Write(buf)
+		case HunkData:
+			dataBase[i] = uint32(data.Len())
+			data.Write(buf)
+		case HunkBSS:
+			bssBase[i] = bssSize
+			bssSize += h.Size
+		}
+	}
+
+	var strtab bytes.Buffer
+	strtab.Write(make([]byte, 4)) // length prefix, patched in below
+	var symtab bytes.Buffer
+
+	for i, h := range f.Hunks {
+		var base uint32
+		var typ byte
+		switch h.Type {
+		case HunkCode:
+			base, typ = textBase[i], aoutNText
+		case HunkData:
+			base, typ = dataBase[i], aoutNData
+		case HunkBSS:
+			base, typ = bssBase[i], aoutNBSS
+		default:
+			continue
+		}
+
+		for _, s := range h.Symbols {
+			strx := uint32(strtab.Len())
+			strtab.WriteString(s.Name)
+			strtab.WriteByte(0)
+
+			var entry [12]byte
+			binary.BigEndian.PutUint32(entry[0:4], strx)
+			entry[4] = typ | aoutNExt
+			binary.BigEndian.PutUint32(entry[8:12], base+s.Value)
+			symtab.Write(entry[:])
+		}
+	}
+
+	strBytes := strtab.Bytes()
+	binary.BigEndian.PutUint32(strBytes[0:4], uint32(len(strBytes)))
+
+	var header [aoutHeaderSize]byte
+	binary.BigEndian.PutUint32(header[0:4], aoutOMAGIC)
+	binary.BigEndian.PutUint32(header[4:8], uint32(text.Len()))
+	binary.BigEndian.PutUint32(header[8:12], uint32(data.Len()))
+	binary.BigEndian.PutUint32(header[12:16], bssSize)
+	binary.BigEndian.PutUint32(header[16:20], uint32(symtab.Len()))
+
+	var out bytes.Buffer
+	out.Write(header[:])
+	out.Write(text.Bytes())
+	out.Write(data.Bytes())
+	out.Write(symtab.Bytes())
+	out.Write(strBytes)
+	return out.Bytes(), nil
+}
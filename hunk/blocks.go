@@ -0,0 +1,149 @@
+package hunk
+
+import "fmt"
+
+// readReloc reads one relocation block (HUNK_RELOC32/16/8 or its
+// hunk-relative HUNK_DREL32/16/8 counterpart) into a slice of Reloc
+// groups, terminated by a group count of zero.
+//
+// The 32-bit blocks use long word counts, hunk numbers and offsets.
+// The 16- and 8-bit blocks use word-sized counts and hunk numbers, and
+// word- or byte-sized offsets respectively; the block is padded to a
+// long word boundary once the terminating zero count is read.
+func readReloc(b *reader, width int, delta bool) ([]Reloc, error) {
+	var relocs []Reloc
+
+	if width == 32 {
+		for {
+			n := b.nextLongWord()
+			if n == 0 || b.err != nil {
+				return relocs, b.Err()
+			}
+			r := Reloc{Width: 32, Delta: delta, HunkIndex: int(b.nextLongWord())}
+			for i := 0; i < int(n) && b.err == nil; i++ {
+				r.Offsets = append(r.Offsets, b.nextLongWord())
+			}
+			relocs = append(relocs, r)
+			if b.err != nil {
+				return relocs, b.Err()
+			}
+		}
+	}
+
+	for {
+		n := b.nextWord()
+		if n == 0 || b.err != nil {
+			b.align()
+			return relocs, b.Err()
+		}
+		r := Reloc{Width: width, Delta: delta, HunkIndex: int(b.nextWord())}
+		for i := 0; i < int(n) && b.err == nil; i++ {
+			if width == 16 {
+				r.Offsets = append(r.Offsets, uint32(b.nextWord()))
+			} else {
+				r.Offsets = append(r.Offsets, uint32(b.nextByte()))
+			}
+		}
+		relocs = append(relocs, r)
+		if b.err != nil {
+			return relocs, b.Err()
+		}
+	}
+}
+
+// readExternals reads the entries of a HUNK_EXT block, terminated by
+// a type/length long word of zero.
+func readExternals(b *reader) ([]External, error) {
+	var exts []External
+
+	for {
+		typeLen := b.nextLongWord()
+		if typeLen == 0 || b.err != nil {
+			return exts, b.Err()
+		}
+
+		kind := ExtKind(typeLen >> 24)
+		name := trimNulls(b.nextBytes((typeLen & 0x00FFFFFF) * LongWordSize))
+		e := External{Kind: kind, Name: name}
+
+		switch kind {
+		case ExtDef, ExtAbs, ExtRes:
+			e.Value = b.nextLongWord()
+		case ExtRef32, ExtRef16, ExtRef8:
+			n := b.nextLongWord()
+			for i := 0; i < int(n) && b.err == nil; i++ {
+				e.Refs = append(e.Refs, b.nextLongWord())
+			}
+		case ExtCommon:
+			e.Value = b.nextLongWord()
+			n := b.nextLongWord()
+			for i := 0; i < int(n) && b.err == nil; i++ {
+				e.Refs = append(e.Refs, b.nextLongWord())
+			}
+		default:
+			return nil, fmt.Errorf("hunk: unsupported HUNK_EXT kind %d", kind)
+		}
+
+		if b.err != nil {
+			return exts, b.Err()
+		}
+		exts = append(exts, e)
+	}
+}
+
+// readSymbols reads the name/value pairs of a HUNK_SYMBOL block,
+// terminated by a name length of zero.
+func readSymbols(b *reader) ([]Symbol, error) {
+	var syms []Symbol
+
+	for {
+		n := b.nextLongWord()
+		if n == 0 || b.err != nil {
+			return syms, b.Err()
+		}
+		name := trimNulls(b.nextBytes(n * LongWordSize))
+		value := b.nextLongWord()
+		if b.err != nil {
+			return syms, b.Err()
+		}
+		syms = append(syms, Symbol{Name: name, Value: value})
+	}
+}
+
+// readDebug reads a single HUNK_DEBUG block: a size, an offset within
+// the hunk the information applies to, and a payload that is either
+// one of the recognized LINE/HCLN sub-formats or, most commonly,
+// unparsed gcc stabs data.
+func readDebug(b *reader) (Debug, error) {
+	size := b.nextLongWord() * LongWordSize
+	if size < LongWordSize {
+		return Debug{}, fmt.Errorf("hunk: HUNK_DEBUG block too small")
+	}
+
+	d := Debug{Offset: b.nextLongWord()}
+	payload := b.nextBytes(size - LongWordSize)
+	if b.err != nil {
+		return Debug{}, b.Err()
+	}
+
+	if len(payload) >= 4 {
+		if tag := string(payload[:4]); tag == "LINE" || tag == "HCLN" {
+			d.Kind = tag
+			d.Data = payload[4:]
+			return d, nil
+		}
+	}
+
+	d.Data = payload
+	return d, nil
+}
+
+// trimNulls returns raw as a string with any trailing NUL padding
+// bytes removed, as used to pad Hunk strings to a long word boundary.
+func trimNulls(raw []byte) string {
+	end := len(raw)
+	for end > 0 && raw[end-1] == 0 {
+		end--
+	}
+	return string(raw[:end])
+}
@@ -0,0 +1,219 @@
+package hunk
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Insn is a single decoded 68000 instruction, as produced by Disasm.
+type Insn struct {
+	Addr     uint32 // address of the first byte of the instruction
+	Bytes    []byte
+	Mnemonic string
+	Operands string
+
+	// Annotation holds extra context appended when printing the
+	// instruction: the name of a symbol a branch targets, or the
+	// hunk+offset a relocated operand points at (e.g. "-> hunk2+0x40").
+	Annotation string
+
+	// branchTarget, when set by a branch/jump decoder, is the
+	// absolute address the instruction transfers control to; used by
+	// Disasm to look it up in the symbol table.
+	branchTarget *uint32
+}
+
+// String formats an instruction the way Disasm's caller is expected
+// to print it: "address: bytes  mnemonic operands", with any
+// Annotation appended.
+func (in Insn) String() string {
+	var hexBytes string
+	for _, b := range in.Bytes {
+		hexBytes += fmt.Sprintf("%02x", b)
+	}
+	s := fmt.Sprintf("%08x: %-12s %s %s", in.Addr, hexBytes, in.Mnemonic, in.Operands)
+	if in.Annotation != "" {
+		s += " " + in.Annotation
+	}
+	return s
+}
+
+// decoder walks a code buffer one instruction at a time.
+type decoder struct {
+	code []byte
+	pos  int
+	base uint32
+}
+
+func (d *decoder) addr() uint32 {
+	return d.base + uint32(d.pos)
+}
+
+func (d *decoder) remaining() int {
+	return len(d.code) - d.pos
+}
+
+func (d *decoder) word(off int) uint16 {
+	if off+1 >= len(d.code) {
+		return 0
+	}
+	return uint16(d.code[off])<<8 | uint16(d.code[off+1])
+}
+
+// nextWord consumes and returns the next 16-bit extension word. A
+// truncated instruction (not enough bytes left in code) reads as 0
+// rather than panicking, so a malformed or padded trailing opcode
+// can't crash the scan.
+func (d *decoder) nextWord() uint16 {
+	w := d.word(d.pos)
+	d.pos += 2
+	if d.pos > len(d.code) {
+		d.pos = len(d.code)
+	}
+	return w
+}
+
+// nextLong consumes and returns the next 32-bit extension long word.
+func (d *decoder) nextLong() uint32 {
+	hi := uint32(d.nextWord())
+	lo := uint32(d.nextWord())
+	return hi<<16 | lo
+}
+
+// opPattern matches a 16-bit opcode word against mask/value and, on a
+// match, decodes the full instruction (consuming any extension words
+// it needs from d). The table is ordered most-specific mask first;
+// adding 68020+ addressing modes or instructions is just a matter of
+// adding more entries.
+type opPattern struct {
+	mask, value uint16
+	decode      func(d *decoder, opcode uint16) Insn
+}
+
+// opTable is ordered most-specific mask first, so that a broad
+// catch-all (like decodeMove's "top two bits are 00") never shadows a
+// narrower pattern (like the immediate-instruction group) that also
+// falls inside it.
+//
+// Coverage is not exhaustive: ABCD, ADDX, ADDA/SUBA/CMPA (the
+// address-register forms of the dyadic ALU ops) and MULU/MULS/DIVU/
+// DIVS are not specially decoded. They share an opmode/mode encoding
+// slot with the entries decodeDyadic and decodeCmpEor handle, and
+// those decoders emit the ".dc.w" placeholder for them. CMPM, which
+// shares EOR's encoding with an address-register mode, is not
+// rejected and prints as an "eor" with an address register operand.
+var opTable = []opPattern{
+	{0xFFFF, 0x4E71, func(d *decoder, op uint16) Insn { return Insn{Mnemonic: "nop"} }},
+	{0xFFFF, 0x4E75, func(d *decoder, op uint16) Insn { return Insn{Mnemonic: "rts"} }},
+	{0xFFFF, 0x4E73, func(d *decoder, op uint16) Insn { return Insn{Mnemonic: "rte"} }},
+	{0xFFFF, 0x4E70, func(d *decoder, op uint16) Insn { return Insn{Mnemonic: "reset"} }},
+	{0xFFFF, 0x4E72, func(d *decoder, op uint16) Insn {
+		return Insn{Mnemonic: "stop", Operands: fmt.Sprintf("#%#x", d.nextWord())}
+	}},
+	{0xFFFF, 0x4AFC, func(d *decoder, op uint16) Insn { return Insn{Mnemonic: "illegal"} }},
+	{0xFFF0, 0x4E40, decodeTrap},
+	{0xF1C0, 0x41C0, decodeLea},
+	{0xFFC0, 0x4EC0, decodeJmp},
+	{0xFFC0, 0x4E80, decodeJsr},
+	{0xFF00, 0x0000, decodeImmediate}, // ORI
+	{0xFF00, 0x0200, decodeImmediate}, // ANDI
+	{0xFF00, 0x0400, decodeImmediate}, // SUBI
+	{0xFF00, 0x0600, decodeImmediate}, // ADDI
+	{0xFF00, 0x0C00, decodeImmediate}, // CMPI
+	{0xFFF8, 0x4880, decodeExt("w")},  // EXT.W
+	{0xFFF8, 0x48C0, decodeExt("l")},  // EXT.L
+	{0xFFF8, 0x4840, decodeSwap},
+	{0xFFF8, 0x4E50, decodeLink},
+	{0xFFF8, 0x4E58, decodeUnlk},
+	{0xF1F8, 0xC140, decodeExg}, // EXG Dn,Dn / An,An / Dn,An (disambiguated inside decodeExg)
+	{0xF1F8, 0xC148, decodeExg},
+	{0xF1F8, 0xC188, decodeExg},
+	{0xFF80, 0x4880, decodeMovem(true)},  // MOVEM reglist,<ea>
+	{0xFF80, 0x4C80, decodeMovem(false)}, // MOVEM <ea>,reglist
+	{0xFF00, 0x4200, decodeUnary("clr")},
+	{0xFF00, 0x4400, decodeUnary("neg")},
+	{0xFF00, 0x4600, decodeUnary("not")},
+	{0xFF00, 0x4A00, decodeUnary("tst")},
+	{0xF000, 0x5000, decodeQuick}, // ADDQ/SUBQ/Scc/DBcc
+	{0xF000, 0x6000, decodeBcc},
+	{0xF000, 0x7000, decodeMoveq},
+	{0xF000, 0x8000, decodeDyadic("or")},
+	{0xF000, 0x9000, decodeDyadic("sub")},
+	{0xF000, 0xB000, decodeCmpEor},
+	{0xF000, 0xC000, decodeDyadic("and")},
+	{0xF000, 0xD000, decodeDyadic("add")},
+	{0xC000, 0x0000, decodeMove}, // MOVE.b/w/l, includes MOVEA as a special case
+}
+
+// Disasm decodes the bytes in code, which begin at address base, as a
+// sequence of 68000 instructions. relocs and syms, normally taken
+// from the HUNK_RELOC32 and HUNK_SYMBOL blocks attached to the same
+// hunk, are used to annotate branch targets and relocated operands
+// with symbol names or a "hunk+offset" cross-reference. Any word that
+// doesn't match a known opcode is emitted as a single ".dc.w"
+// pseudo-instruction so that the scan can keep making progress.
+func Disasm(code []byte, base uint32, relocs []Reloc, syms []Symbol) []Insn {
+	d := &decoder{code: code, base: base}
+	var insns []Insn
+
+	relocOffsets := make(map[uint32]Reloc)
+	for _, r := range relocs {
+		if r.Width != 32 {
+			continue
+		}
+		for _, off := range r.Offsets {
+			relocOffsets[off] = r
+		}
+	}
+
+	for d.remaining() >= 2 {
+		start := d.pos
+		opcode := d.nextWord()
+
+		in := decodeOne(d, opcode)
+		in.Addr = d.base + uint32(start)
+		in.Bytes = d.code[start:d.pos]
+
+		annotate(&in, code, uint32(start), relocOffsets, syms)
+		insns = append(insns, in)
+	}
+
+	return insns
+}
+
+// decodeOne looks up opcode in opTable and runs its decoder,
+// producing a ".dc.w" placeholder for anything unrecognized.
+func decodeOne(d *decoder, opcode uint16) Insn {
+	for _, p := range opTable {
+		if opcode&p.mask == p.value {
+			return p.decode(d, opcode)
+		}
+	}
+	return Insn{Mnemonic: ".dc.w", Operands: fmt.Sprintf("#%#04x", opcode)}
+}
+
+// annotate fills in in.Annotation from either a relocation at the
+// instruction's operand long word, or (for branches) a symbol
+// matching the computed target address.
+func annotate(in *Insn, code []byte, startOff uint32, relocOffsets map[uint32]Reloc, syms []Symbol) {
+	// A relocation touching any long word within this instruction
+	// means the operand refers to another hunk. The long word already
+	// in the code is the link-time offset within that target hunk.
+	for off := startOff; off < startOff+uint32(len(in.Bytes)); off++ {
+		if r, ok := relocOffsets[off]; ok && int(off)+4 <= len(code) {
+			target := binary.BigEndian.Uint32(code[off : off+4])
+			in.Annotation = fmt.Sprintf("-> hunk%d+%#x", r.HunkIndex, target)
+			return
+		}
+	}
+
+	if in.branchTarget == nil {
+		return
+	}
+	for _, s := range syms {
+		if s.Value == *in.branchTarget {
+			in.Annotation = "-> " + s.Name
+			return
+		}
+	}
+}
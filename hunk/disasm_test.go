@@ -0,0 +1,67 @@
+package hunk
+
+import "testing"
+
+func TestDisasm(t *testing.T) {
+	cases := []struct {
+		name     string
+		code     []byte
+		mnemonic string
+		operands string
+	}{
+		{"nop", []byte{0x4E, 0x71}, "nop", ""},
+		{"rts", []byte{0x4E, 0x75}, "rts", ""},
+		{"moveq", []byte{0x70, 0x05}, "moveq", "#5,d0"},
+		{"bra.s", []byte{0x60, 0x02}, "bra", "$1004"},
+		{"unknown opcode falls back to dc.w", []byte{0xFF, 0xFF}, ".dc.w", "#0xffff"},
+		{"and.w", []byte{0xC0, 0x41}, "and.w", "d1,d0"},
+		{"add.w to ea", []byte{0xD1, 0x51}, "add.w", "d0,(a1)"},
+		{"cmp.w", []byte{0xB4, 0x50}, "cmp.w", "(a0),d2"},
+		{"eor.w", []byte{0xB7, 0x44}, "eor.w", "d3,d4"},
+		{"clr.w", []byte{0x42, 0x45}, "clr.w", "d5"},
+		{"tst.l", []byte{0x4A, 0x86}, "tst.l", "d6"},
+		{"not.w", []byte{0x46, 0x47}, "not.w", "d7"},
+		{"neg.b", []byte{0x44, 0x00}, "neg.b", "d0"},
+		{"exg", []byte{0xC3, 0x42}, "exg", "d1,d2"},
+		{"swap", []byte{0x48, 0x43}, "swap", "d3"},
+		{"ext.w", []byte{0x48, 0x84}, "ext.w", "d4"},
+		{"link", []byte{0x4E, 0x55, 0xFF, 0xFC}, "link", "a5,#-4"},
+		{"unlk", []byte{0x4E, 0x5E}, "unlk", "a6"},
+		{"movem.l store", []byte{0x48, 0xD0, 0x01, 0x03}, "movem.l", "d0-d1/a0,(a0)"},
+		{"movem.w load", []byte{0x4C, 0x91, 0x00, 0x0C}, "movem.w", "(a1),d2-d3"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			insns := Disasm(c.code, 0x1000, nil, nil)
+			if len(insns) != 1 {
+				t.Fatalf("got %d instructions, want 1", len(insns))
+			}
+			in := insns[0]
+			if in.Mnemonic != c.mnemonic {
+				t.Errorf("Mnemonic = %q, want %q", in.Mnemonic, c.mnemonic)
+			}
+			if in.Operands != c.operands {
+				t.Errorf("Operands = %q, want %q", in.Operands, c.operands)
+			}
+			if in.Addr != 0x1000 {
+				t.Errorf("Addr = %#x, want %#x", in.Addr, 0x1000)
+			}
+		})
+	}
+}
+
+func TestDisasmAnnotatesRelocatedOperand(t *testing.T) {
+	// lea $0.l,a0: 41F9 00000000, with a HUNK_RELOC32 entry at offset 2
+	// (the long-word operand) pointing at hunk 1.
+	code := []byte{0x41, 0xF9, 0x00, 0x00, 0x00, 0x00}
+	relocs := []Reloc{{Width: 32, HunkIndex: 1, Offsets: []uint32{2}}}
+
+	insns := Disasm(code, 0, relocs, nil)
+	if len(insns) != 1 {
+		t.Fatalf("got %d instructions, want 1", len(insns))
+	}
+	if insns[0].Annotation == "" {
+		t.Errorf("Annotation is empty, want a hunk cross-reference")
+	}
+}
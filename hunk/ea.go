@@ -0,0 +1,432 @@
+package hunk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// condNames gives the standard 68000 mnemonic suffix for each of the
+// 16 condition codes, as used by Scc and DBcc ("s"+name, "db"+name).
+// Bcc special-cases conditions 0 and 1 as "bra"/"bsr" instead.
+var condNames = [16]string{
+	"t", "f", "hi", "ls", "cc", "cs", "ne", "eq",
+	"vc", "vs", "pl", "mi", "ge", "lt", "gt", "le",
+}
+
+// decodeEA formats the effective address given by mode/reg, consuming
+// any extension words it needs from d. size is the operand size in
+// bytes (1, 2 or 4), used to size an immediate or absolute operand.
+// It also returns the address an absolute-long operand resolves to,
+// or nil for every other addressing mode.
+func decodeEA(d *decoder, mode, reg uint16, size int) (string, *uint32) {
+	switch mode {
+	case 0:
+		return fmt.Sprintf("d%d", reg), nil
+	case 1:
+		return fmt.Sprintf("a%d", reg), nil
+	case 2:
+		return fmt.Sprintf("(a%d)", reg), nil
+	case 3:
+		return fmt.Sprintf("(a%d)+", reg), nil
+	case 4:
+		return fmt.Sprintf("-(a%d)", reg), nil
+	case 5:
+		disp := int16(d.nextWord())
+		return fmt.Sprintf("%d(a%d)", disp, reg), nil
+	case 6:
+		ext := d.nextWord()
+		idxReg := (ext >> 12) & 7
+		idxName := "d"
+		if ext&0x8000 != 0 {
+			idxName = "a"
+		}
+		idxSize := "w"
+		if ext&0x0800 != 0 {
+			idxSize = "l"
+		}
+		disp := int8(ext & 0xFF)
+		return fmt.Sprintf("%d(a%d,%s%d.%s)", disp, reg, idxName, idxReg, idxSize), nil
+	case 7:
+		switch reg {
+		case 0:
+			v := d.nextWord()
+			return fmt.Sprintf("$%x.w", v), nil
+		case 1:
+			v := d.nextLong()
+			return fmt.Sprintf("$%x.l", v), &v
+		case 2:
+			disp := int16(d.nextWord())
+			return fmt.Sprintf("%d(pc)", disp), nil
+		case 3:
+			ext := d.nextWord()
+			disp := int8(ext & 0xFF)
+			return fmt.Sprintf("%d(pc,ext=%#x)", disp, ext), nil
+		case 4:
+			switch size {
+			case 1:
+				return fmt.Sprintf("#$%x", d.nextWord()&0xFF), nil
+			case 2:
+				return fmt.Sprintf("#$%x", d.nextWord()), nil
+			default:
+				return fmt.Sprintf("#$%x", d.nextLong()), nil
+			}
+		}
+	}
+	return "?", nil
+}
+
+// sizeFromBits maps a standard 68000 size field (00=byte, 01=word,
+// 10=long) to its byte count and mnemonic suffix, as used by both the
+// immediate group and ADDQ/SUBQ. ok is false for the reserved 11
+// encoding.
+func sizeFromBits(bits uint16) (size int, name string, ok bool) {
+	switch bits {
+	case 0:
+		return 1, "b", true
+	case 1:
+		return 2, "w", true
+	case 2:
+		return 4, "l", true
+	default:
+		return 0, "", false
+	}
+}
+
+func decodeTrap(d *decoder, op uint16) Insn {
+	return Insn{Mnemonic: "trap", Operands: fmt.Sprintf("#%d", op&0xF)}
+}
+
+func decodeLea(d *decoder, op uint16) Insn {
+	an := (op >> 9) & 7
+	mode := (op >> 3) & 7
+	reg := op & 7
+	ea, _ := decodeEA(d, mode, reg, 4)
+	return Insn{Mnemonic: "lea", Operands: fmt.Sprintf("%s,a%d", ea, an)}
+}
+
+func decodeJmp(d *decoder, op uint16) Insn {
+	mode := (op >> 3) & 7
+	reg := op & 7
+	ea, target := decodeEA(d, mode, reg, 4)
+	return Insn{Mnemonic: "jmp", Operands: ea, branchTarget: target}
+}
+
+func decodeJsr(d *decoder, op uint16) Insn {
+	mode := (op >> 3) & 7
+	reg := op & 7
+	ea, target := decodeEA(d, mode, reg, 4)
+	return Insn{Mnemonic: "jsr", Operands: ea, branchTarget: target}
+}
+
+// decodeBcc decodes BRA/BSR/Bcc: 0110 cccc dddddddd, with a 16-bit
+// displacement following when the byte displacement is zero.
+func decodeBcc(d *decoder, op uint16) Insn {
+	cond := (op >> 8) & 0xF
+	disp8 := int8(op & 0xFF)
+
+	pc := d.addr() // address right after the opcode word
+	var target uint32
+	if disp8 != 0 {
+		target = uint32(int32(pc) + int32(disp8))
+	} else {
+		disp16 := int16(d.nextWord())
+		target = uint32(int32(pc) + int32(disp16))
+	}
+
+	mnemonic := "b" + condNames[cond]
+	switch cond {
+	case 0:
+		mnemonic = "bra"
+	case 1:
+		mnemonic = "bsr"
+	}
+
+	t := target
+	return Insn{Mnemonic: mnemonic, Operands: fmt.Sprintf("$%x", target), branchTarget: &t}
+}
+
+func decodeMoveq(d *decoder, op uint16) Insn {
+	reg := (op >> 9) & 7
+	data := int8(op & 0xFF)
+	return Insn{Mnemonic: "moveq", Operands: fmt.Sprintf("#%d,d%d", data, reg)}
+}
+
+// decodeMove decodes MOVE.b/w/l <ea>,<ea> (and MOVEA when the
+// destination is an address register): 00 ss ddd MMM mmm rrr, size
+// bits 01=byte, 11=word, 10=long.
+func decodeMove(d *decoder, op uint16) Insn {
+	var size int
+	var sizeName string
+	switch (op >> 12) & 3 {
+	case 1:
+		size, sizeName = 1, "b"
+	case 3:
+		size, sizeName = 2, "w"
+	case 2:
+		size, sizeName = 4, "l"
+	default:
+		return Insn{Mnemonic: ".dc.w", Operands: fmt.Sprintf("#%#04x", op)}
+	}
+
+	dstReg := (op >> 9) & 7
+	dstMode := (op >> 6) & 7
+	srcMode := (op >> 3) & 7
+	srcReg := op & 7
+
+	// The source operand's extension words precede the destination's
+	// in the instruction stream, so it must be decoded first.
+	srcText, _ := decodeEA(d, srcMode, srcReg, size)
+	dstText, _ := decodeEA(d, dstMode, dstReg, size)
+
+	mnemonic := "move." + sizeName
+	if dstMode == 1 {
+		mnemonic = "movea." + sizeName
+	}
+	return Insn{Mnemonic: mnemonic, Operands: srcText + "," + dstText}
+}
+
+// decodeQuick handles every instruction whose top nibble is 0101:
+// ADDQ/SUBQ #data,<ea> when the size field isn't 11, and Scc <ea> /
+// DBcc Dn,label when it is (DBcc is the An-direct-mode special case
+// of Scc).
+func decodeQuick(d *decoder, op uint16) Insn {
+	sizeOrCond := (op >> 6) & 3
+	mode := (op >> 3) & 7
+	reg := op & 7
+
+	if sizeOrCond == 3 {
+		cond := (op >> 8) & 0xF
+		if mode == 1 {
+			disp := int16(d.nextWord())
+			extAddr := d.addr() - 2
+			target := uint32(int32(extAddr) + int32(disp))
+			t := target
+			return Insn{
+				Mnemonic:     "db" + condNames[cond],
+				Operands:     fmt.Sprintf("d%d,$%x", reg, target),
+				branchTarget: &t,
+			}
+		}
+		ea, _ := decodeEA(d, mode, reg, 1)
+		return Insn{Mnemonic: "s" + condNames[cond], Operands: ea}
+	}
+
+	data := (op >> 9) & 7
+	if data == 0 {
+		data = 8
+	}
+	isSub := op&0x0100 != 0
+
+	size, sizeName, _ := sizeFromBits(sizeOrCond)
+	ea, _ := decodeEA(d, mode, reg, size)
+	mnemonic := "addq." + sizeName
+	if isSub {
+		mnemonic = "subq." + sizeName
+	}
+	return Insn{Mnemonic: mnemonic, Operands: fmt.Sprintf("#%d,%s", data, ea)}
+}
+
+// decodeImmediate handles ORI/ANDI/SUBI/ADDI/CMPI #imm,<ea>:
+// 0000 ooo0 ss mmm rrr.
+func decodeImmediate(d *decoder, op uint16) Insn {
+	var name string
+	switch (op >> 9) & 7 {
+	case 0:
+		name = "ori"
+	case 1:
+		name = "andi"
+	case 2:
+		name = "subi"
+	case 3:
+		name = "addi"
+	case 6:
+		name = "cmpi"
+	default:
+		return Insn{Mnemonic: ".dc.w", Operands: fmt.Sprintf("#%#04x", op)}
+	}
+
+	size, sizeName, ok := sizeFromBits((op >> 6) & 3)
+	if !ok {
+		return Insn{Mnemonic: ".dc.w", Operands: fmt.Sprintf("#%#04x", op)}
+	}
+
+	var imm string
+	switch size {
+	case 1:
+		imm = fmt.Sprintf("#$%x", d.nextWord()&0xFF)
+	case 2:
+		imm = fmt.Sprintf("#$%x", d.nextWord())
+	default:
+		imm = fmt.Sprintf("#$%x", d.nextLong())
+	}
+
+	mode := (op >> 3) & 7
+	reg := op & 7
+	ea, _ := decodeEA(d, mode, reg, size)
+	return Insn{Mnemonic: name + "." + sizeName, Operands: imm + "," + ea}
+}
+
+// decodeDyadic builds a decoder for the two-operand ALU opcode shape
+// shared by AND/OR/ADD/SUB: 1ooo rrr ppp mmm rrr, where ooo selects
+// the instruction, rrr (bits 11-9) names a data register, ppp (bits
+// 8-6, the "opmode") picks the operand size and direction, and
+// mmm/rrr (bits 5-0) give the other operand's effective address.
+// Opmode 3 and 7 select the word/long address-register forms
+// (ADDA/SUBA) or MULU/MULS/DIVU/DIVS, which aren't decoded here and
+// fall back to the generic ".dc.w" placeholder, as does a
+// register-direct destination for opmode 4-6 (AND and ADD each
+// repurpose that reserved slot for ABCD/EXG or ADDX).
+func decodeDyadic(name string) func(d *decoder, op uint16) Insn {
+	return func(d *decoder, op uint16) Insn {
+		reg := (op >> 9) & 7
+		opmode := (op >> 6) & 7
+		mode := (op >> 3) & 7
+		eaReg := op & 7
+
+		size, sizeName, ok := sizeFromBits(opmode & 3)
+		if !ok {
+			return Insn{Mnemonic: ".dc.w", Operands: fmt.Sprintf("#%#04x", op)}
+		}
+		if opmode >= 4 && mode < 2 {
+			return Insn{Mnemonic: ".dc.w", Operands: fmt.Sprintf("#%#04x", op)}
+		}
+
+		ea, _ := decodeEA(d, mode, eaReg, size)
+		mnemonic := name + "." + sizeName
+		if opmode < 4 {
+			return Insn{Mnemonic: mnemonic, Operands: fmt.Sprintf("%s,d%d", ea, reg)}
+		}
+		return Insn{Mnemonic: mnemonic, Operands: fmt.Sprintf("d%d,%s", reg, ea)}
+	}
+}
+
+// decodeCmpEor handles the 1011 opcode nibble, shared by CMP (opmode
+// 0-2, ea compared against a data register) and EOR (opmode 4-6, a
+// data register XORed into the destination ea). Opmode 3 and 7 select
+// CMPA, not decoded here.
+func decodeCmpEor(d *decoder, op uint16) Insn {
+	reg := (op >> 9) & 7
+	opmode := (op >> 6) & 7
+	mode := (op >> 3) & 7
+	eaReg := op & 7
+
+	size, sizeName, ok := sizeFromBits(opmode & 3)
+	if !ok {
+		return Insn{Mnemonic: ".dc.w", Operands: fmt.Sprintf("#%#04x", op)}
+	}
+
+	ea, _ := decodeEA(d, mode, eaReg, size)
+	if opmode < 4 {
+		return Insn{Mnemonic: "cmp." + sizeName, Operands: fmt.Sprintf("%s,d%d", ea, reg)}
+	}
+	return Insn{Mnemonic: "eor." + sizeName, Operands: fmt.Sprintf("d%d,%s", reg, ea)}
+}
+
+// decodeUnary builds a decoder for the single-operand opcode shape
+// shared by CLR/NEG/NOT/TST: 0100 oooo ss mmm rrr.
+func decodeUnary(name string) func(d *decoder, op uint16) Insn {
+	return func(d *decoder, op uint16) Insn {
+		size, sizeName, ok := sizeFromBits((op >> 6) & 3)
+		if !ok {
+			return Insn{Mnemonic: ".dc.w", Operands: fmt.Sprintf("#%#04x", op)}
+		}
+		mode := (op >> 3) & 7
+		reg := op & 7
+		ea, _ := decodeEA(d, mode, reg, size)
+		return Insn{Mnemonic: name + "." + sizeName, Operands: ea}
+	}
+}
+
+func decodeSwap(d *decoder, op uint16) Insn {
+	return Insn{Mnemonic: "swap", Operands: fmt.Sprintf("d%d", op&7)}
+}
+
+// decodeExt builds a decoder for EXT.W/EXT.L Dn (sign-extend byte to
+// word, or word to long).
+func decodeExt(sizeName string) func(d *decoder, op uint16) Insn {
+	return func(d *decoder, op uint16) Insn {
+		return Insn{Mnemonic: "ext." + sizeName, Operands: fmt.Sprintf("d%d", op&7)}
+	}
+}
+
+func decodeExg(d *decoder, op uint16) Insn {
+	rx := (op >> 9) & 7
+	ry := op & 7
+	switch (op >> 3) & 0x1F {
+	case 0x08:
+		return Insn{Mnemonic: "exg", Operands: fmt.Sprintf("d%d,d%d", rx, ry)}
+	case 0x09:
+		return Insn{Mnemonic: "exg", Operands: fmt.Sprintf("a%d,a%d", rx, ry)}
+	case 0x11:
+		return Insn{Mnemonic: "exg", Operands: fmt.Sprintf("d%d,a%d", rx, ry)}
+	default:
+		return Insn{Mnemonic: ".dc.w", Operands: fmt.Sprintf("#%#04x", op)}
+	}
+}
+
+func decodeLink(d *decoder, op uint16) Insn {
+	disp := int16(d.nextWord())
+	return Insn{Mnemonic: "link", Operands: fmt.Sprintf("a%d,#%d", op&7, disp)}
+}
+
+func decodeUnlk(d *decoder, op uint16) Insn {
+	return Insn{Mnemonic: "unlk", Operands: fmt.Sprintf("a%d", op&7)}
+}
+
+// regListString formats a MOVEM register mask (bit n set means
+// register n is in the list; 0-7 are D0-D7, 8-15 are A0-A7) the way a
+// standard 68000 assembler would: runs of consecutive registers
+// collapsed to "lo-hi", separated by "/".
+func regListString(mask uint16) string {
+	name := func(i int) string {
+		if i < 8 {
+			return fmt.Sprintf("d%d", i)
+		}
+		return fmt.Sprintf("a%d", i-8)
+	}
+
+	var parts []string
+	for i := 0; i < 16; {
+		if mask&(1<<uint(i)) == 0 {
+			i++
+			continue
+		}
+		start := i
+		for i < 16 && mask&(1<<uint(i)) != 0 && i/8 == start/8 {
+			i++
+		}
+		if end := i - 1; end == start {
+			parts = append(parts, name(start))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s-%s", name(start), name(end)))
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// decodeMovem builds a decoder for MOVEM <reglist>,<ea> (toMem) or
+// MOVEM <ea>,<reglist>. The register-list mask word always follows
+// the opcode word, before any of the ea's own extension words. For
+// predecrement mode the mask is conventionally read in reverse
+// register order (A7 down to D0); that reversal isn't applied here,
+// so the printed list for a "movem.l d0-d7/a0-a6,-(sp)" style
+// prologue save may read back to front.
+func decodeMovem(toMem bool) func(d *decoder, op uint16) Insn {
+	return func(d *decoder, op uint16) Insn {
+		mask := d.nextWord()
+		size, sizeName := 2, "w"
+		if op&0x0040 != 0 {
+			size, sizeName = 4, "l"
+		}
+		mode := (op >> 3) & 7
+		reg := op & 7
+		ea, _ := decodeEA(d, mode, reg, size)
+		regs := regListString(mask)
+
+		mnemonic := "movem." + sizeName
+		if toMem {
+			return Insn{Mnemonic: mnemonic, Operands: regs + "," + ea}
+		}
+		return Insn{Mnemonic: mnemonic, Operands: ea + "," + regs}
+	}
+}
@@ -0,0 +1,220 @@
+package hunk
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+)
+
+// m68k ELF relocation types relevant to object files produced by the
+// amigaos-cross-toolchain. debug/elf does not define these, as it has
+// no notion of the m68k machine.
+const (
+	rTyp68kNone  = 0
+	rType68k32   = 1 // absolute, full 32 bits
+	rType68kPC32 = 4 // PC-relative, full 32 bits
+)
+
+// FromELF builds a Hunk File out of an m68k ELF object file, mapping
+// each loadable section to a HUNK_CODE, HUNK_DATA or HUNK_BSS hunk:
+// executable PROGBITS sections become code, other PROGBITS sections
+// become data, and NOBITS sections become BSS. chip lists the ELF
+// section names (e.g. ".text", ".data") that must be allocated from
+// Amiga chip memory; every other section is allocated from fast
+// memory (MemFast).
+func FromELF(ef *elf.File, chip map[string]bool) (*File, error) {
+	f := &File{}
+	sectionHunk := make(map[int]int)
+	raw := make(map[int][]byte)
+
+	for i, sec := range ef.Sections {
+		if sec.Flags&elf.SHF_ALLOC == 0 {
+			continue
+		}
+
+		var hunkType uint32
+		var data []byte
+		switch {
+		case sec.Type == elf.SHT_NOBITS:
+			hunkType = HunkBSS
+		case sec.Flags&elf.SHF_EXECINSTR != 0:
+			hunkType = HunkCode
+		case sec.Type == elf.SHT_PROGBITS:
+			hunkType = HunkData
+		default:
+			continue
+		}
+
+		if hunkType != HunkBSS {
+			d, err := sec.Data()
+			if err != nil {
+				return nil, fmt.Errorf("hunk: reading section %s: %w", sec.Name, err)
+			}
+			data = d
+		}
+
+		memType := MemFast
+		if chip[sec.Name] {
+			memType = MemChip
+		}
+
+		// Hunk sizes are always a whole number of long words (the size
+		// field in HUNK_CODE/DATA/BSS and the hunk table is a long word
+		// count), but an ELF section's byte size need not be a multiple
+		// of 4. Pad rather than truncate, or NewFile would read back a
+		// shorter hunk than was written here.
+		size := padToLongWord(uint32(sec.Size))
+		if hunkType != HunkBSS && uint32(len(data)) < size {
+			data = append(data, make([]byte, size-uint32(len(data)))...)
+		}
+
+		h := &Hunk{Type: hunkType, Size: size, MemType: memType}
+		if hunkType != HunkBSS {
+			raw[len(f.Hunks)] = data
+			h.ReaderAt = bytes.NewReader(data)
+		}
+
+		sectionHunk[i] = len(f.Hunks)
+		f.Hunks = append(f.Hunks, h)
+	}
+
+	// A missing symbol table just means no symbols or relocations can
+	// be resolved; it isn't fatal.
+	symbols, _ := ef.Symbols()
+
+	symHunk := make(map[int]int) // ELF symtab index (1-based) -> hunk index
+	for i, sym := range symbols {
+		hi, ok := sectionHunk[int(sym.Section)]
+		if !ok || sym.Name == "" {
+			continue
+		}
+		f.Hunks[hi].Symbols = append(f.Hunks[hi].Symbols, Symbol{
+			Name:  sym.Name,
+			Value: uint32(sym.Value),
+		})
+		symHunk[i+1] = hi
+	}
+
+	for i, sec := range ef.Sections {
+		hi, ok := sectionHunk[i]
+		if !ok {
+			continue
+		}
+		relSec := findRelocSection(ef, i)
+		if relSec == nil {
+			continue
+		}
+		relocs, err := decodeRelocs(relSec, symbols, symHunk, raw[hi])
+		if err != nil {
+			return nil, fmt.Errorf("hunk: relocating section %s: %w", sec.Name, err)
+		}
+		f.Hunks[hi].Relocs = append(f.Hunks[hi].Relocs, relocs...)
+	}
+
+	if len(f.Hunks) > 0 {
+		f.LastHunk = uint32(len(f.Hunks) - 1)
+	}
+	for _, h := range f.Hunks {
+		f.HunkSizes = append(f.HunkSizes, h.Size)
+	}
+
+	return f, nil
+}
+
+// padToLongWord rounds n up to the next multiple of LongWordSize.
+func padToLongWord(n uint32) uint32 {
+	if rem := n % LongWordSize; rem != 0 {
+		n += LongWordSize - rem
+	}
+	return n
+}
+
+// findRelocSection returns the SHT_REL/SHT_RELA section that applies
+// to section index target, or nil if there is none.
+func findRelocSection(ef *elf.File, target int) *elf.Section {
+	for _, sec := range ef.Sections {
+		if (sec.Type == elf.SHT_REL || sec.Type == elf.SHT_RELA) && int(sec.Info) == target {
+			return sec
+		}
+	}
+	return nil
+}
+
+// decodeRelocs reads the Rel32/Rela32 entries of relSec and translates
+// R_68K_32 and R_68K_PC32 relocations into HUNK_RELOC32/HUNK_DREL32
+// groups, one group per target hunk. When an addend isn't stored
+// in-place (SHT_RELA), it is added into data at the relocation offset,
+// matching the Hunk convention of baking relocation addends into the
+// hunk's bytes.
+func decodeRelocs(relSec *elf.Section, symbols []elf.Symbol, symHunk map[int]int, data []byte) ([]Reloc, error) {
+	raw, err := relSec.Data()
+	if err != nil {
+		return nil, err
+	}
+
+	byTarget := make(map[int]*Reloc)
+	order := []int{}
+
+	addReloc := func(off uint32, targetHunk int, delta bool) {
+		r, ok := byTarget[targetHunk]
+		if !ok {
+			r = &Reloc{Width: 32, Delta: delta, HunkIndex: targetHunk}
+			byTarget[targetHunk] = r
+			order = append(order, targetHunk)
+		}
+		r.Offsets = append(r.Offsets, off)
+	}
+
+	switch relSec.Type {
+	case elf.SHT_REL:
+		for i := 0; i+8 <= len(raw); i += 8 {
+			var rel elf.Rel32
+			rel.Off = binary.BigEndian.Uint32(raw[i : i+4])
+			rel.Info = binary.BigEndian.Uint32(raw[i+4 : i+8])
+			if err := applyReloc(rel.Off, rel.Info, 0, symHunk, addReloc); err != nil {
+				return nil, err
+			}
+		}
+	case elf.SHT_RELA:
+		for i := 0; i+12 <= len(raw); i += 12 {
+			off := binary.BigEndian.Uint32(raw[i : i+4])
+			info := binary.BigEndian.Uint32(raw[i+4 : i+8])
+			addend := int32(binary.BigEndian.Uint32(raw[i+8 : i+12]))
+			if int(off)+4 <= len(data) {
+				cur := binary.BigEndian.Uint32(data[off : off+4])
+				binary.BigEndian.PutUint32(data[off:off+4], cur+uint32(addend))
+			}
+			if err := applyReloc(off, info, addend, symHunk, addReloc); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	relocs := make([]Reloc, 0, len(order))
+	for _, target := range order {
+		relocs = append(relocs, *byTarget[target])
+	}
+	return relocs, nil
+}
+
+// applyReloc classifies a single relocation entry by its ELF type and
+// feeds its offset to add for the resolved target hunk.
+func applyReloc(off, info uint32, addend int32, symHunk map[int]int, add func(off uint32, targetHunk int, delta bool)) error {
+	symIdx := int(elf.R_SYM32(info))
+	hi, ok := symHunk[symIdx]
+	if !ok {
+		return nil // reference to an external/undefined symbol: left unresolved
+	}
+
+	switch elf.R_TYPE32(info) {
+	case rType68k32:
+		add(off, hi, false)
+	case rType68kPC32:
+		add(off, hi, true)
+	case rTyp68kNone:
+	default:
+		return fmt.Errorf("unsupported m68k relocation type %d", elf.R_TYPE32(info))
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package hunk
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"testing"
+)
+
+// elfMachine68K is debug/elf's EM_68K; the package exposes no named
+// constant for it.
+const elfMachine68K = 4
+
+// buildM68kObject assembles a minimal big-endian ELF32 relocatable
+// object file by hand (debug/elf has no writer), with:
+//   - .text: 6 bytes of code (deliberately not a multiple of 4, to
+//     exercise the long-word padding FromELF must apply)
+//   - .data: 5 bytes of data holding one R_68K_32 relocation that
+//     targets the symbol "start" in .text
+//   - .bss: 10 bytes, no file content
+//   - a symbol table naming "start" (in .text) and "blob" (in .data)
+//
+// The returned bytes can be parsed with elf.NewFile to get a real
+// *elf.File backed by working Section.Data()/Symbols(), which a
+// hand-built elf.Section cannot provide (its Data method reads
+// through an unexported field, not the embedded ReaderAt).
+func buildM68kObject(t *testing.T) []byte {
+	t.Helper()
+
+	const (
+		shstrtabIdx = 7
+	)
+
+	textData := []byte{0x70, 0x00, 0x4E, 0x75, 0xAA, 0xBB} // moveq #0,d0 ; rts ; 2 trailing bytes
+	dataData := []byte{0, 0, 0, 0, 0xCC}                   // a relocated long word + 1 trailing byte
+
+	// Section name string table.
+	var shstrtab bytes.Buffer
+	shstrtab.WriteByte(0)
+	nameOff := map[string]uint32{}
+	for _, name := range []string{".text", ".data", ".bss", ".symtab", ".strtab", ".rela.data", ".shstrtab"} {
+		nameOff[name] = uint32(shstrtab.Len())
+		shstrtab.WriteString(name)
+		shstrtab.WriteByte(0)
+	}
+
+	// Symbol string table and symbol table.
+	var strtab bytes.Buffer
+	strtab.WriteByte(0)
+	symNameOff := map[string]uint32{}
+	for _, name := range []string{"start", "blob"} {
+		symNameOff[name] = uint32(strtab.Len())
+		strtab.WriteString(name)
+		strtab.WriteByte(0)
+	}
+
+	var symtab bytes.Buffer
+	writeSym := func(s elf.Sym32) {
+		binary.Write(&symtab, binary.BigEndian, s)
+	}
+	writeSym(elf.Sym32{}) // index 0: the required null symbol
+	writeSym(elf.Sym32{Name: symNameOff["start"], Value: 0, Info: uint8(elf.STT_FUNC) | uint8(elf.STB_GLOBAL)<<4, Shndx: 1})
+	writeSym(elf.Sym32{Name: symNameOff["blob"], Value: 0, Info: uint8(elf.STT_OBJECT) | uint8(elf.STB_GLOBAL)<<4, Shndx: 2})
+
+	// One R_68K_32 relocation at .data offset 0, targeting symbol 1
+	// ("start"), with the addend already baked into dataData.
+	var rela bytes.Buffer
+	binary.Write(&rela, binary.BigEndian, elf.Rela32{Off: 0, Info: uint32(1)<<8 | rType68k32, Addend: 0})
+
+	type section struct {
+		name  string
+		typ   elf.SectionType
+		flags elf.SectionFlag
+		data  []byte
+		link  uint32
+		info  uint32
+	}
+	sections := []section{
+		{}, // SHT_NULL
+		{name: ".text", typ: elf.SHT_PROGBITS, flags: elf.SHF_ALLOC | elf.SHF_EXECINSTR, data: textData},
+		{name: ".data", typ: elf.SHT_PROGBITS, flags: elf.SHF_ALLOC | elf.SHF_WRITE, data: dataData},
+		{name: ".bss", typ: elf.SHT_NOBITS, flags: elf.SHF_ALLOC | elf.SHF_WRITE, data: nil},
+		{name: ".symtab", typ: elf.SHT_SYMTAB, data: symtab.Bytes(), link: 5, info: 2}, // 2 local-or-earlier syms before the first global isn't tracked; info unused by decodeRelocs
+		{name: ".strtab", typ: elf.SHT_STRTAB, data: strtab.Bytes()},
+		{name: ".rela.data", typ: elf.SHT_RELA, data: rela.Bytes(), link: 4, info: 2}, // applies to section 2 (.data), symbols in section 4
+		{name: ".shstrtab", typ: elf.SHT_STRTAB, data: shstrtab.Bytes()},
+	}
+
+	const ehsize = 52
+	const shentsize = 40
+	shoff := uint32(ehsize)
+
+	// Lay out section contents right after the section header table.
+	dataOff := shoff + uint32(len(sections))*shentsize
+	offsets := make([]uint32, len(sections))
+	for i, s := range sections {
+		if s.typ == elf.SHT_NOBITS || s.typ == elf.SHT_NULL {
+			continue
+		}
+		offsets[i] = dataOff
+		dataOff += uint32(len(s.data))
+	}
+
+	var buf bytes.Buffer
+	ident := [elf.EI_NIDENT]byte{}
+	copy(ident[:4], elf.ELFMAG)
+	ident[elf.EI_CLASS] = byte(elf.ELFCLASS32)
+	ident[elf.EI_DATA] = byte(elf.ELFDATA2MSB)
+	ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+
+	hdr := elf.Header32{
+		Ident:     ident,
+		Type:      uint16(elf.ET_REL),
+		Machine:   elfMachine68K,
+		Version:   uint32(elf.EV_CURRENT),
+		Shoff:     shoff,
+		Ehsize:    ehsize,
+		Shentsize: shentsize,
+		Shnum:     uint16(len(sections)),
+		Shstrndx:  shstrtabIdx,
+	}
+	binary.Write(&buf, binary.BigEndian, hdr)
+
+	for i, s := range sections {
+		sz := uint32(len(s.data))
+		if s.typ == elf.SHT_NOBITS {
+			sz = 10
+		}
+		binary.Write(&buf, binary.BigEndian, elf.Section32{
+			Name:    nameOff[s.name],
+			Type:    uint32(s.typ),
+			Flags:   uint32(s.flags),
+			Off:     offsets[i],
+			Size:    sz,
+			Link:    s.link,
+			Info:    s.info,
+			Entsize: entsizeFor(s.typ),
+		})
+	}
+
+	for _, s := range sections {
+		if s.typ == elf.SHT_NOBITS || s.typ == elf.SHT_NULL {
+			continue
+		}
+		buf.Write(s.data)
+	}
+
+	return buf.Bytes()
+}
+
+func entsizeFor(typ elf.SectionType) uint32 {
+	switch typ {
+	case elf.SHT_SYMTAB:
+		return 16
+	case elf.SHT_RELA:
+		return 12
+	default:
+		return 0
+	}
+}
+
+func TestFromELF(t *testing.T) {
+	raw := buildM68kObject(t)
+	ef, err := elf.NewFile(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("elf.NewFile: %v", err)
+	}
+
+	f, err := FromELF(ef, map[string]bool{".data": true})
+	if err != nil {
+		t.Fatalf("FromELF: %v", err)
+	}
+
+	if len(f.Hunks) != 3 {
+		t.Fatalf("got %d hunks, want 3 (text, data, bss)", len(f.Hunks))
+	}
+
+	text, data, bss := f.Hunks[0], f.Hunks[1], f.Hunks[2]
+
+	if text.Type != HunkCode {
+		t.Errorf("hunk 0 Type = %v, want HunkCode", text.Type)
+	}
+	if text.Size != 8 {
+		t.Errorf("hunk 0 (.text) Size = %d, want 8 (6 bytes padded to a long word)", text.Size)
+	}
+	if text.MemType != MemFast {
+		t.Errorf("hunk 0 (.text) MemType = %v, want MemFast", text.MemType)
+	}
+
+	if data.Type != HunkData {
+		t.Errorf("hunk 1 Type = %v, want HunkData", data.Type)
+	}
+	if data.Size != 8 {
+		t.Errorf("hunk 1 (.data) Size = %d, want 8 (5 bytes padded to a long word)", data.Size)
+	}
+	if data.MemType != MemChip {
+		t.Errorf("hunk 1 (.data) MemType = %v, want MemChip (requested via chip map)", data.MemType)
+	}
+
+	if bss.Type != HunkBSS {
+		t.Errorf("hunk 2 Type = %v, want HunkBSS", bss.Type)
+	}
+	if bss.Size != 12 {
+		t.Errorf("hunk 2 (.bss) Size = %d, want 12 (10 bytes padded to a long word)", bss.Size)
+	}
+
+	var gotSyms []string
+	for _, h := range f.Hunks {
+		for _, s := range h.Symbols {
+			gotSyms = append(gotSyms, s.Name)
+		}
+	}
+	if len(gotSyms) != 2 {
+		t.Fatalf("got symbols %v, want 2 entries (start, blob)", gotSyms)
+	}
+
+	if len(data.Relocs) != 1 {
+		t.Fatalf("got %d relocs on .data, want 1", len(data.Relocs))
+	}
+	r := data.Relocs[0]
+	if r.Width != 32 || r.Delta || r.HunkIndex != 0 || len(r.Offsets) != 1 || r.Offsets[0] != 0 {
+		t.Errorf("reloc = %+v, want {Width:32 Delta:false HunkIndex:0 Offsets:[0]}", r)
+	}
+}
+
+func TestToAOUT(t *testing.T) {
+	f := &File{
+		FileHeader: FileHeader{FirstHunk: 0, LastHunk: 2},
+		Hunks: []*Hunk{
+			{Type: HunkCode, Size: 4, ReaderAt: bytes.NewReader([]byte{0x4E, 0x71, 0x4E, 0x75}), Symbols: []Symbol{{Name: "start", Value: 0}}},
+			{Type: HunkData, Size: 4, ReaderAt: bytes.NewReader([]byte{1, 2, 3, 4}), Symbols: []Symbol{{Name: "blob", Value: 0}}},
+			{Type: HunkBSS, Size: 8},
+		},
+	}
+
+	out, err := ToAOUT(f)
+	if err != nil {
+		t.Fatalf("ToAOUT: %v", err)
+	}
+	if len(out) < aoutHeaderSize {
+		t.Fatalf("output too short: %d bytes", len(out))
+	}
+
+	magic := binary.BigEndian.Uint32(out[0:4])
+	if magic != aoutOMAGIC {
+		t.Errorf("magic = %#x, want %#x", magic, aoutOMAGIC)
+	}
+	textSize := binary.BigEndian.Uint32(out[4:8])
+	dataSize := binary.BigEndian.Uint32(out[8:12])
+	bssSize := binary.BigEndian.Uint32(out[12:16])
+	symSize := binary.BigEndian.Uint32(out[16:20])
+
+	if textSize != 4 {
+		t.Errorf("text size = %d, want 4", textSize)
+	}
+	if dataSize != 4 {
+		t.Errorf("data size = %d, want 4", dataSize)
+	}
+	if bssSize != 8 {
+		t.Errorf("bss size = %d, want 8", bssSize)
+	}
+	if symSize != 2*12 {
+		t.Errorf("symtab size = %d, want %d (2 entries)", symSize, 2*12)
+	}
+
+	body := out[aoutHeaderSize:]
+	if !bytes.Equal(body[:4], []byte{0x4E, 0x71, 0x4E, 0x75}) {
+		t.Errorf("text segment = % x, want 4e714e75", body[:4])
+	}
+	if !bytes.Equal(body[4:8], []byte{1, 2, 3, 4}) {
+		t.Errorf("data segment = % x, want 01020304", body[4:8])
+	}
+
+	if !bytes.Contains(out, []byte("start\x00")) {
+		t.Errorf("output missing symbol name %q", "start")
+	}
+	if !bytes.Contains(out, []byte("blob\x00")) {
+		t.Errorf("output missing symbol name %q", "blob")
+	}
+}
@@ -0,0 +1,31 @@
+package hunk
+
+// ExtKind identifies the sub-kind of a single entry within a HUNK_EXT
+// block: either a definition made available to other hunks, or a
+// reference to a symbol defined elsewhere.
+type ExtKind uint8
+
+const (
+	ExtDef    ExtKind = 1   // symbol defined here, value follows
+	ExtAbs    ExtKind = 2   // absolute symbol definition
+	ExtRes    ExtKind = 3   // resident library symbol definition
+	ExtRef32  ExtKind = 129 // 32-bit references to an external symbol
+	ExtCommon ExtKind = 130 // common block definition
+	ExtRef16  ExtKind = 131 // 16-bit references to an external symbol
+	ExtRef8   ExtKind = 132 // 8-bit references to an external symbol
+)
+
+// External is one entry of a HUNK_EXT block: either the definition of
+// a symbol exported by this hunk, or a list of offsets within this
+// hunk that reference a symbol defined elsewhere.
+//
+// For ExtDef, ExtAbs and ExtRes, Value is the symbol's value. For
+// ExtCommon, Value is the size in bytes of the common block. For the
+// ExtRef* kinds, Refs holds the offsets, within this hunk, of each
+// reference to Name that must be resolved by the linker.
+type External struct {
+	Kind  ExtKind  `json:"kind"`
+	Name  string   `json:"name"`
+	Value uint32   `json:"value,omitempty"`
+	Refs  []uint32 `json:"refs,omitempty"`
+}
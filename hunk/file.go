@@ -0,0 +1,212 @@
+package hunk
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// magic is the long word every Hunk executable file must begin with
+// (HUNK_HEADER, 0x3F3).
+const magic = 0x000003F3
+
+// FileHeader holds the fields of the HUNK_HEADER block that precedes
+// the hunk table in every Hunk executable file.
+type FileHeader struct {
+	// HunkSizes holds the memory size in bytes of every hunk listed in
+	// the hunk table, in file order.
+	HunkSizes []uint32
+	FirstHunk uint32
+	LastHunk  uint32
+}
+
+// File represents an open Hunk file.
+type File struct {
+	FileHeader
+	Hunks []*Hunk
+
+	closer io.Closer
+}
+
+// Open opens the named file using os.Open and prepares it for access
+// as a Hunk file.
+//
+// If the File was created using NewFile directly instead of Open, Close
+// has no effect.
+func Open(name string) (*File, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	ff, err := NewFile(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	ff.closer = f
+	return ff, nil
+}
+
+// Close closes the File. If the File was created using NewFile
+// directly instead of Open, Close has no effect.
+func (f *File) Close() error {
+	var err error
+	if f.closer != nil {
+		err = f.closer.Close()
+		f.closer = nil
+	}
+	return err
+}
+
+// NewFile creates a new File for accessing a Hunk binary in an
+// underlying reader. The Hunk binary is expected to start at
+// position 0 in the ReaderAt.
+func NewFile(r io.ReaderAt) (*File, error) {
+	content, err := io.ReadAll(io.NewSectionReader(r, 0, 1<<63-1))
+	if err != nil {
+		return nil, err
+	}
+
+	b := &reader{stream: content}
+	switch first := b.nextLongWord(); first {
+	case magic:
+		// fall through to executable parsing below
+	case HunkUnit:
+		return nil, fmt.Errorf("hunk: file starts with HUNK_UNIT; object files are not supported, only executables")
+	default:
+		return nil, fmt.Errorf("hunk: bad magic number %#x, not a Hunk executable", first)
+	}
+
+	f := new(File)
+
+	if b.nextLongWord() != 0 {
+		return nil, fmt.Errorf("hunk: resident library lists are not supported")
+	}
+
+	f.FirstHunk = b.nextLongWord()
+	f.LastHunk = b.nextLongWord()
+	if f.LastHunk < f.FirstHunk {
+		return nil, fmt.Errorf("hunk: last hunk %d precedes first hunk %d", f.LastHunk, f.FirstHunk)
+	}
+	totalHunks := f.LastHunk - f.FirstHunk + 1
+	// Each hunk table entry and each hunk body is at least one long
+	// word, so a file can't possibly hold more hunks than it has long
+	// words; reject a bogus count up front rather than looping (or
+	// allocating) anywhere near that far.
+	if uint64(totalHunks) > uint64(len(content))/LongWordSize {
+		return nil, fmt.Errorf("hunk: implausible hunk count %d", totalHunks)
+	}
+
+	for i := 0; i < int(totalHunks); i++ {
+		_, size := decodeSize(b.nextLongWord())
+		f.HunkSizes = append(f.HunkSizes, size)
+	}
+	if b.Err() != nil {
+		return nil, fmt.Errorf("hunk: reading hunk table: %w", b.Err())
+	}
+
+	for i := 0; i < int(totalHunks); i++ {
+		h, err := readHunk(b, r)
+		if err != nil {
+			return nil, fmt.Errorf("hunk %d: %w", i, err)
+		}
+		f.Hunks = append(f.Hunks, h)
+	}
+
+	return f, nil
+}
+
+// readHunk reads a single HUNK_CODE, HUNK_DATA or HUNK_BSS block,
+// along with every auxiliary block (name, relocations, externals,
+// symbols, debug info, overlay) up to the terminating HUNK_END, from
+// b. base is the ReaderAt the whole file was read from, used to hand
+// out a section-local ReaderAt for the hunk's data.
+func readHunk(b *reader, base io.ReaderAt) (*Hunk, error) {
+	h := new(Hunk)
+
+	for {
+		hunkType := b.nextLongWord()
+		if b.Err() != nil {
+			return nil, b.Err()
+		}
+
+		switch hunkType {
+		case HunkName:
+			name, err := b.nextString()
+			if err != nil {
+				return nil, err
+			}
+			h.Name = name
+		case HunkCode, HunkData:
+			h.Type = hunkType
+			memType, size := decodeSize(b.nextLongWord())
+			h.MemType = memType
+			if memType == MemAdditional {
+				h.AddMemFlags = b.nextLongWord()
+			}
+			h.Size = size
+			h.ReaderAt = io.NewSectionReader(base, int64(b.offset), int64(size))
+			b.advance(uint(size))
+		case HunkBSS:
+			h.Type = hunkType
+			memType, size := decodeSize(b.nextLongWord())
+			h.MemType = memType
+			if memType == MemAdditional {
+				h.AddMemFlags = b.nextLongWord()
+			}
+			h.Size = size
+		case HunkReloc32, HunkDrel32:
+			reloc, err := readReloc(b, 32, hunkType == HunkDrel32)
+			if err != nil {
+				return nil, err
+			}
+			h.Relocs = append(h.Relocs, reloc...)
+		case HunkReloc16, HunkDrel16:
+			reloc, err := readReloc(b, 16, hunkType == HunkDrel16)
+			if err != nil {
+				return nil, err
+			}
+			h.Relocs = append(h.Relocs, reloc...)
+		case HunkReloc8, HunkDrel8:
+			reloc, err := readReloc(b, 8, hunkType == HunkDrel8)
+			if err != nil {
+				return nil, err
+			}
+			h.Relocs = append(h.Relocs, reloc...)
+		case HunkExt:
+			exts, err := readExternals(b)
+			if err != nil {
+				return nil, err
+			}
+			h.Externals = append(h.Externals, exts...)
+		case HunkSymbol:
+			syms, err := readSymbols(b)
+			if err != nil {
+				return nil, err
+			}
+			h.Symbols = append(h.Symbols, syms...)
+		case HunkDebug:
+			d, err := readDebug(b)
+			if err != nil {
+				return nil, err
+			}
+			h.Debug = append(h.Debug, d)
+		case HunkOverlay:
+			size := b.nextLongWord() * LongWordSize
+			h.Overlay = b.nextBytes(size)
+		case HunkBreak:
+			h.HasBreak = true
+		case HunkEnd:
+			if b.Err() != nil {
+				return nil, b.Err()
+			}
+			return h, nil
+		default:
+			return nil, fmt.Errorf("unexpected block type %#x", hunkType)
+		}
+
+		if b.Err() != nil {
+			return nil, b.Err()
+		}
+	}
+}
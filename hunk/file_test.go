@@ -0,0 +1,117 @@
+package hunk
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteToNewFileRoundTrip(t *testing.T) {
+	f := &File{
+		FileHeader: FileHeader{HunkSizes: []uint32{8, 0}, FirstHunk: 0, LastHunk: 1},
+		Hunks: []*Hunk{
+			{
+				Type:    HunkCode,
+				Size:    8,
+				MemType: MemChip,
+				Name:    "text",
+				ReaderAt: bytes.NewReader([]byte{
+					0, 0, 0, 0, 0, 0, 0, 0,
+				}),
+				Relocs:    []Reloc{{Width: 32, HunkIndex: 1, Offsets: []uint32{0}}},
+				Externals: []External{{Kind: ExtDef, Name: "foo", Value: 4}},
+				Symbols:   []Symbol{{Name: "start", Value: 0}},
+				Debug:     []Debug{{Offset: 0, Data: []byte{1, 2, 3}}},
+				HasBreak:  true,
+			},
+			{Type: HunkBSS, Size: 4, MemType: MemFast},
+		},
+	}
+
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+
+	got, err := NewFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewFile: %v", err)
+	}
+
+	if len(got.Hunks) != 2 {
+		t.Fatalf("got %d hunks, want 2", len(got.Hunks))
+	}
+
+	h0 := got.Hunks[0]
+	if h0.Type != HunkCode || h0.MemType != MemChip || h0.Name != "text" {
+		t.Errorf("hunk 0 = %+v", h0)
+	}
+	if len(h0.Relocs) != 1 || h0.Relocs[0].HunkIndex != 1 {
+		t.Errorf("hunk 0 relocs = %+v", h0.Relocs)
+	}
+	if len(h0.Externals) != 1 || h0.Externals[0].Name != "foo" {
+		t.Errorf("hunk 0 externals = %+v", h0.Externals)
+	}
+	if len(h0.Symbols) != 1 || h0.Symbols[0].Name != "start" {
+		t.Errorf("hunk 0 symbols = %+v", h0.Symbols)
+	}
+	// writeHunk pads Debug.Data to a long word boundary, so the 3-byte
+	// payload comes back with a trailing zero.
+	if len(h0.Debug) != 1 || !bytes.Equal(h0.Debug[0].Data, []byte{1, 2, 3, 0}) {
+		t.Errorf("hunk 0 debug = %+v", h0.Debug)
+	}
+	if !h0.HasBreak {
+		t.Errorf("hunk 0 HasBreak = false, want true")
+	}
+
+	h1 := got.Hunks[1]
+	if h1.Type != HunkBSS || h1.MemType != MemFast || h1.Size != 4 {
+		t.Errorf("hunk 1 = %+v", h1)
+	}
+}
+
+func TestNewFileRejectsObjectFile(t *testing.T) {
+	var buf bytes.Buffer
+	wr := &writer{}
+	wr.long(HunkUnit)
+	wr.string("unit")
+
+	_, err := NewFile(bytes.NewReader(append(buf.Bytes(), wr.buf.Bytes()...)))
+	if err == nil {
+		t.Fatal("NewFile on a HUNK_UNIT-led object file should fail, got no error")
+	}
+}
+
+// TestNewFileRejectsOversizedHunkSize reproduces a file whose
+// HUNK_CODE block claims a size far larger than the actual data that
+// follows it: NewFile must return an error, not panic slicing the
+// underlying byte stream.
+func TestNewFileRejectsOversizedHunkSize(t *testing.T) {
+	var wr writer
+	wr.long(magic)
+	wr.long(0) // no resident libs
+	wr.long(0) // first hunk
+	wr.long(0) // last hunk
+	wr.long(encodeSize(MemAny, 0x7FFFFFFF))
+	wr.long(HunkCode)
+	wr.long(encodeSize(MemAny, 0x7FFFFFFF))
+
+	_, err := NewFile(bytes.NewReader(wr.buf.Bytes()))
+	if err == nil {
+		t.Fatal("NewFile on an oversized hunk size should fail, got no error")
+	}
+}
+
+// TestNewFileRejectsTruncatedFile exercises the same bounds-check
+// path against a file that simply stops mid-hunk-table.
+func TestNewFileRejectsTruncatedFile(t *testing.T) {
+	var wr writer
+	wr.long(magic)
+	wr.long(0)
+	wr.long(0)
+	wr.long(3) // claims 4 hunks, but no hunk table or hunk data follows
+
+	_, err := NewFile(bytes.NewReader(wr.buf.Bytes()))
+	if err == nil {
+		t.Fatal("NewFile on a truncated file should fail, got no error")
+	}
+}
@@ -0,0 +1,213 @@
+// Package hunk implements access to AmigaOS Hunk executable files, as
+// described in "The AmigaDOS Manual", Chapter 10. Hunk object files,
+// which are led by a HUNK_UNIT block rather than a HUNK_HEADER and can
+// bundle several units, are not supported; NewFile rejects them with
+// an explicit error rather than attempting to parse them.
+//
+// The API is modeled after the standard library's debug/elf and
+// debug/macho packages: a File is obtained with NewFile or Open, and
+// exposes its contents as a slice of typed Hunks.
+package hunk
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// LongWordSize is the size in bytes of an AmigaOS long word, the unit
+// most Hunk fields and block sizes are expressed in.
+const LongWordSize = 4
+
+// Block type identifiers, as they appear as the first long word of
+// every Hunk block.
+const (
+	HunkUnit    = 0x000003E7
+	HunkName    = 0x000003E8
+	HunkCode    = 0x000003E9
+	HunkData    = 0x000003EA
+	HunkBSS     = 0x000003EB
+	HunkReloc32 = 0x000003EC
+	HunkReloc16 = 0x000003ED
+	HunkReloc8  = 0x000003EE
+	HunkExt     = 0x000003EF
+	HunkSymbol  = 0x000003F0
+	HunkDebug   = 0x000003F1
+	HunkEnd     = 0x000003F2
+	HunkHeader  = 0x000003F3
+	HunkOverlay = 0x000003F5
+	HunkBreak   = 0x000003F6
+	HunkDrel32  = 0x000003FC
+	HunkDrel16  = 0x000003FD
+	HunkDrel8   = 0x000003FE
+)
+
+// hunkTypeNames maps block type identifiers to a short human-readable
+// description, used when printing a Hunk.
+var hunkTypeNames = map[uint32]string{
+	HunkUnit:    "Start of program unit",
+	HunkName:    "Name block",
+	HunkCode:    "Code block",
+	HunkData:    "Initialized data block",
+	HunkBSS:     "Uninitialized data block",
+	HunkReloc32: "32-bit relocation information",
+	HunkReloc16: "16-bit relocation information",
+	HunkReloc8:  "8-bit relocation information",
+	HunkExt:     "External references/definitions",
+	HunkSymbol:  "Debugging symbols",
+	HunkDebug:   "Debugging information",
+	HunkEnd:     "End block of a hunk",
+	HunkHeader:  "Start of executable file",
+	HunkOverlay: "Overlay block",
+	HunkBreak:   "Overlay break block",
+	HunkDrel32:  "32-bit hunk-relative relocation information",
+	HunkDrel16:  "16-bit hunk-relative relocation information",
+	HunkDrel8:   "8-bit hunk-relative relocation information",
+}
+
+// TypeName returns a short human-readable description of a block type
+// identifier, or "Unknown hunk block type" if it isn't recognized.
+func TypeName(hunkType uint32) string {
+	name, ok := hunkTypeNames[hunkType]
+	if !ok {
+		return "Unknown hunk block type"
+	}
+	return name
+}
+
+// MemType is the memory type requested for a hunk, encoded in the top
+// two bits of its size long word (and, for MemAdditional, refined by
+// an extra long word of MEMF_* flags immediately following).
+type MemType uint8
+
+const (
+	MemAny        MemType = iota // no particular requirement
+	MemChip                      // must be allocated from chip memory
+	MemFast                      // must be allocated from fast memory
+	MemAdditional                // exact MEMF_* flags follow in AddMemFlags
+)
+
+func (m MemType) String() string {
+	switch m {
+	case MemAny:
+		return "any"
+	case MemChip:
+		return "chip"
+	case MemFast:
+		return "fast"
+	case MemAdditional:
+		return "additional"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalJSON encodes a MemType as its String() name, so a JSON dump
+// reads as "chip"/"fast" rather than an opaque small integer.
+func (m MemType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON is the inverse of MarshalJSON.
+func (m *MemType) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	for mt := MemAny; mt <= MemAdditional; mt++ {
+		if mt.String() == name {
+			*m = mt
+			return nil
+		}
+	}
+	return fmt.Errorf("hunk: unknown memory type %q", name)
+}
+
+// memTypeShift and memTypeSizeMask split a hunk size long word into
+// its MemType (top two bits) and long word count (the rest).
+const (
+	memTypeShift    = 30
+	memTypeSizeMask = 0x3FFFFFFF
+)
+
+// decodeSize splits a raw hunk size long word, as found in the hunk
+// table or before a HUNK_CODE/DATA/BSS block, into its memory type and
+// its size in bytes.
+func decodeSize(raw uint32) (MemType, uint32) {
+	return MemType(raw >> memTypeShift), (raw & memTypeSizeMask) * LongWordSize
+}
+
+// Reloc holds one group of relocations of a given Width (8, 16 or 32
+// bits) targeting a single hunk: the value at each offset in Offsets
+// must be relocated against the base address of hunk HunkIndex. Delta
+// relocations (from a HUNK_DREL* block) are relative to the start of
+// the referenced hunk rather than its load address.
+type Reloc struct {
+	Width     int      `json:"width"`
+	Delta     bool     `json:"delta,omitempty"`
+	HunkIndex int      `json:"hunkIndex"`
+	Offsets   []uint32 `json:"offsets"`
+}
+
+// Symbol is a name/value pair from a HUNK_SYMBOL block, normally used
+// by debuggers to map addresses back to the names of code or data
+// symbols.
+type Symbol struct {
+	Name  string `json:"name"`
+	Value uint32 `json:"value"`
+}
+
+// Debug holds the contents of one HUNK_DEBUG block attached to a hunk.
+// Offset is the position within the hunk the information refers to.
+// Kind identifies a recognized sub-format ("LINE" or "HCLN"); it is
+// empty for the common case of gcc stabs debug info, which has no
+// leading tag and is passed through in Data unparsed.
+type Debug struct {
+	Offset uint32 `json:"offset"`
+	Kind   string `json:"kind,omitempty"`
+	Data   []byte `json:"data"`
+}
+
+// Hunk is a single loadable unit within a Hunk file (HUNK_CODE,
+// HUNK_DATA or HUNK_BSS), together with the blocks that apply to it:
+// relocations, external references/definitions, symbols and debug
+// info.
+type Hunk struct {
+	Type    uint32
+	Size    uint32 // size in bytes of the hunk's memory image
+	MemType MemType
+
+	// AddMemFlags holds the exact MEMF_* flags requested for the hunk
+	// when MemType is MemAdditional; it is zero otherwise.
+	AddMemFlags uint32
+
+	// Name is the hunk's name, taken from an optional HUNK_NAME block
+	// preceding it. It is normally only present in object files.
+	Name string
+
+	Relocs    []Reloc
+	Externals []External
+	Symbols   []Symbol
+	Debug     []Debug
+
+	// Overlay holds the raw payload of a HUNK_OVERLAY block, if one is
+	// present, in the format expected by the AmigaDOS overlay manager.
+	// It is left unparsed.
+	Overlay []byte
+
+	// HasBreak records whether a HUNK_BREAK marker, splitting an
+	// overlay into segments, was found for this hunk.
+	HasBreak bool
+
+	// ReaderAt gives access to the raw bytes of the hunk (its code or
+	// initialized data). It is nil for HUNK_BSS, which has no stored
+	// data.
+	ReaderAt io.ReaderAt
+}
+
+// Open returns an io.SectionReader over the Hunk's data, starting at
+// offset 0 and with length Size. Reading from it panics if the Hunk
+// has no ReaderAt, as is the case for HUNK_BSS.
+func (h *Hunk) Open() *io.SectionReader {
+	return io.NewSectionReader(h.ReaderAt, 0, int64(h.Size))
+}
@@ -0,0 +1,128 @@
+package hunk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// jsonVersion is the schema version written by ToJSON and checked by
+// FromJSON. Bump it whenever a field is added, removed or changes
+// meaning in a way that breaks older consumers.
+const jsonVersion = 1
+
+// jsonFile is the on-disk shape of ToJSON's output: the file header,
+// the hunk table, and every hunk together with the blocks attached to
+// it.
+type jsonFile struct {
+	Version   int        `json:"version"`
+	FirstHunk uint32     `json:"firstHunk"`
+	LastHunk  uint32     `json:"lastHunk"`
+	HunkSizes []uint32   `json:"hunkSizes"`
+	Hunks     []jsonHunk `json:"hunks"`
+}
+
+// jsonHunk mirrors Hunk, replacing the ReaderAt with the hunk's raw
+// bytes (base64-encoded by encoding/json, as for any []byte field).
+// Data is omitted for HUNK_BSS, which has no stored bytes.
+type jsonHunk struct {
+	Type        uint32     `json:"type"`
+	MemType     MemType    `json:"memType"`
+	AddMemFlags uint32     `json:"addMemFlags,omitempty"`
+	Name        string     `json:"name,omitempty"`
+	Size        uint32     `json:"size"`
+	Data        []byte     `json:"data,omitempty"`
+	Relocs      []Reloc    `json:"relocs,omitempty"`
+	Externals   []External `json:"externals,omitempty"`
+	Symbols     []Symbol   `json:"symbols,omitempty"`
+	Debug       []Debug    `json:"debug,omitempty"`
+	Overlay     []byte     `json:"overlay,omitempty"`
+	HasBreak    bool       `json:"hasBreak,omitempty"`
+}
+
+// ToJSON serializes f into the versioned JSON dump format: the file
+// header, the hunk table, and every block attached to each hunk
+// (code/data bytes, relocations, externals, symbols, debug info). The
+// result is meant for diff viewers, test harnesses or fuzzers that
+// want to consume a parsed Hunk file without reparsing it; the
+// inverse is FromJSON.
+func (f *File) ToJSON() ([]byte, error) {
+	jf := jsonFile{
+		Version:   jsonVersion,
+		FirstHunk: f.FirstHunk,
+		LastHunk:  f.LastHunk,
+		HunkSizes: f.HunkSizes,
+	}
+
+	for i, h := range f.Hunks {
+		jh := jsonHunk{
+			Type:        h.Type,
+			MemType:     h.MemType,
+			AddMemFlags: h.AddMemFlags,
+			Name:        h.Name,
+			Size:        h.Size,
+			Relocs:      h.Relocs,
+			Externals:   h.Externals,
+			Symbols:     h.Symbols,
+			Debug:       h.Debug,
+			Overlay:     h.Overlay,
+			HasBreak:    h.HasBreak,
+		}
+
+		if h.Type != HunkBSS && h.Size > 0 {
+			data := make([]byte, h.Size)
+			if _, err := io.ReadFull(h.Open(), data); err != nil {
+				return nil, fmt.Errorf("hunk %d: %w", i, err)
+			}
+			jh.Data = data
+		}
+
+		jf.Hunks = append(jf.Hunks, jh)
+	}
+
+	return json.MarshalIndent(jf, "", "  ")
+}
+
+// FromJSON parses the output of ToJSON back into a File. The
+// resulting Hunks have their ReaderAt backed by the decoded code/data
+// bytes held in memory, rather than the original Hunk binary.
+func FromJSON(data []byte) (*File, error) {
+	var jf jsonFile
+	if err := json.Unmarshal(data, &jf); err != nil {
+		return nil, err
+	}
+	if jf.Version != jsonVersion {
+		return nil, fmt.Errorf("hunk: unsupported JSON schema version %d", jf.Version)
+	}
+
+	f := &File{
+		FileHeader: FileHeader{
+			HunkSizes: jf.HunkSizes,
+			FirstHunk: jf.FirstHunk,
+			LastHunk:  jf.LastHunk,
+		},
+	}
+
+	for _, jh := range jf.Hunks {
+		h := &Hunk{
+			Type:        jh.Type,
+			Size:        jh.Size,
+			MemType:     jh.MemType,
+			AddMemFlags: jh.AddMemFlags,
+			Name:        jh.Name,
+			Relocs:      jh.Relocs,
+			Externals:   jh.Externals,
+			Symbols:     jh.Symbols,
+			Debug:       jh.Debug,
+			Overlay:     jh.Overlay,
+			HasBreak:    jh.HasBreak,
+		}
+		if jh.Data != nil {
+			h.ReaderAt = bytes.NewReader(jh.Data)
+		}
+		f.Hunks = append(f.Hunks, h)
+	}
+
+	return f, nil
+}
@@ -0,0 +1,101 @@
+package hunk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestLoadAppliesRelocations(t *testing.T) {
+	code := make([]byte, 8)
+	binary.BigEndian.PutUint32(code[4:], 0) // placeholder, relocated below
+
+	f := &File{Hunks: []*Hunk{
+		{Type: HunkCode, Size: 8, ReaderAt: bytes.NewReader(code), Relocs: []Reloc{
+			{Width: 32, HunkIndex: 1, Offsets: []uint32{4}},
+		}},
+		{Type: HunkData, Size: 4, ReaderAt: bytes.NewReader([]byte{0, 0, 0, 0})},
+	}}
+
+	segs, err := f.Load(map[int]uint32{0: 0x1000, 1: 0x2000})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(segs) != 2 {
+		t.Fatalf("got %d segments, want 2", len(segs))
+	}
+	if segs[0].Base != 0x1000 || segs[1].Base != 0x2000 {
+		t.Fatalf("bases = %#x, %#x", segs[0].Base, segs[1].Base)
+	}
+	if got := binary.BigEndian.Uint32(segs[0].Data[4:]); got != 0x2000 {
+		t.Errorf("relocated long word = %#x, want %#x", got, 0x2000)
+	}
+}
+
+func TestLoadAutoAssignsSeparateChipAndFastPools(t *testing.T) {
+	f := &File{Hunks: []*Hunk{
+		{Type: HunkCode, Size: 8, MemType: MemChip, ReaderAt: bytes.NewReader(make([]byte, 8))},
+		{Type: HunkData, Size: 8, MemType: MemFast, ReaderAt: bytes.NewReader(make([]byte, 8))},
+		{Type: HunkCode, Size: 4, MemType: MemChip, ReaderAt: bytes.NewReader(make([]byte, 4))},
+	}}
+
+	segs, err := f.Load(nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if segs[0].Base != defaultChipBase {
+		t.Errorf("chip hunk 0 base = %#x, want %#x", segs[0].Base, defaultChipBase)
+	}
+	if segs[1].Base != defaultFastBase {
+		t.Errorf("fast hunk 1 base = %#x, want %#x", segs[1].Base, defaultFastBase)
+	}
+	// The second chip hunk must be packed after the first chip hunk,
+	// not after the fast hunk that was allocated in between.
+	if want := uint32(defaultChipBase) + 8; segs[2].Base != want {
+		t.Errorf("chip hunk 2 base = %#x, want %#x", segs[2].Base, want)
+	}
+}
+
+func TestToJSONFromJSONRoundTrip(t *testing.T) {
+	orig := &File{
+		FileHeader: FileHeader{HunkSizes: []uint32{4}, FirstHunk: 0, LastHunk: 0},
+		Hunks: []*Hunk{
+			{
+				Type:     HunkCode,
+				Size:     4,
+				MemType:  MemChip,
+				ReaderAt: bytes.NewReader([]byte{1, 2, 3, 4}),
+				Symbols:  []Symbol{{Name: "start", Value: 0}},
+			},
+		},
+	}
+
+	data, err := orig.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON: %v", err)
+	}
+
+	got, err := FromJSON(data)
+	if err != nil {
+		t.Fatalf("FromJSON: %v", err)
+	}
+
+	if len(got.Hunks) != 1 {
+		t.Fatalf("got %d hunks, want 1", len(got.Hunks))
+	}
+	h := got.Hunks[0]
+	if h.Type != HunkCode || h.MemType != MemChip {
+		t.Errorf("hunk = %+v", h)
+	}
+	gotData := make([]byte, 4)
+	if _, err := h.Open().Read(gotData); err != nil {
+		t.Fatalf("Open/Read: %v", err)
+	}
+	if !bytes.Equal(gotData, []byte{1, 2, 3, 4}) {
+		t.Errorf("data = %v, want [1 2 3 4]", gotData)
+	}
+	if len(h.Symbols) != 1 || h.Symbols[0].Name != "start" {
+		t.Errorf("symbols = %+v", h.Symbols)
+	}
+}
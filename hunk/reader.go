@@ -0,0 +1,103 @@
+package hunk
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// reader keeps a byte stream and the current read position in it,
+// used while parsing a Hunk file. Every read method bounds-checks
+// against the stream before slicing; once a read runs past the end of
+// the stream, reader remembers the error (the first one seen) and all
+// further reads return a zero value without touching offset again, so
+// a malformed or truncated file fails with an error instead of
+// panicking.
+type reader struct {
+	stream []byte
+	offset uint
+	err    error
+}
+
+// need reports whether n more bytes are available at the current
+// offset. If not, it records the first error seen (further calls are
+// no-ops) and returns false.
+func (b *reader) need(n uint64) bool {
+	if b.err != nil {
+		return false
+	}
+	if n > uint64(len(b.stream)) || uint64(b.offset) > uint64(len(b.stream))-n {
+		b.err = fmt.Errorf("hunk: unexpected end of data at offset %d (need %d more bytes)", b.offset, n)
+		return false
+	}
+	return true
+}
+
+// Err returns the first error encountered by a read on b, or nil if
+// every read so far stayed within the stream.
+func (b *reader) Err() error {
+	return b.err
+}
+
+func (b *reader) nextLongWord() uint32 {
+	if !b.need(LongWordSize) {
+		return 0
+	}
+	v := binary.BigEndian.Uint32(b.stream[b.offset : b.offset+LongWordSize])
+	b.offset += LongWordSize
+	return v
+}
+
+func (b *reader) nextWord() uint16 {
+	if !b.need(2) {
+		return 0
+	}
+	v := binary.BigEndian.Uint16(b.stream[b.offset : b.offset+2])
+	b.offset += 2
+	return v
+}
+
+func (b *reader) nextByte() byte {
+	if !b.need(1) {
+		return 0
+	}
+	v := b.stream[b.offset]
+	b.offset++
+	return v
+}
+
+func (b *reader) nextBytes(n uint32) []byte {
+	if !b.need(uint64(n)) {
+		return nil
+	}
+	v := b.stream[b.offset : b.offset+uint(n)]
+	b.offset += uint(n)
+	return v
+}
+
+func (b *reader) advance(n uint) {
+	if !b.need(uint64(n)) {
+		return
+	}
+	b.offset += n
+}
+
+// align rounds the read position up to the next long word boundary,
+// as required after block payloads whose length isn't itself a
+// multiple of a long word (e.g. short relocation lists).
+func (b *reader) align() {
+	if rem := b.offset % LongWordSize; rem != 0 {
+		b.advance(LongWordSize - rem)
+	}
+}
+
+// nextString reads a Hunk-style length-prefixed string: a long word
+// holding the length in long words, followed by that many bytes of
+// ASCII text padded with trailing NUL bytes.
+func (b *reader) nextString() (string, error) {
+	n := b.nextLongWord() * LongWordSize
+	s := trimNulls(b.nextBytes(n))
+	if b.err != nil {
+		return "", b.err
+	}
+	return s, nil
+}
@@ -0,0 +1,207 @@
+package hunk
+
+import "io"
+
+// encodeSize packs a hunk's long word count and memory type back into
+// the single long word layout used by the hunk table and by
+// HUNK_CODE/DATA/BSS block headers, the inverse of decodeSize.
+func encodeSize(memType MemType, longWords uint32) uint32 {
+	return longWords | uint32(memType)<<memTypeShift
+}
+
+// WriteTo serializes f back into the AmigaOS Hunk executable format
+// understood by NewFile, writing it to w. It implements io.WriterTo.
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	var wr writer
+
+	wr.long(magic)
+	wr.long(0) // no resident library references
+	wr.long(f.FirstHunk)
+	wr.long(f.LastHunk)
+	for _, h := range f.Hunks {
+		wr.long(encodeSize(h.MemType, h.Size/LongWordSize))
+	}
+
+	for _, h := range f.Hunks {
+		if err := writeHunk(&wr, h); err != nil {
+			return 0, err
+		}
+	}
+
+	return wr.buf.WriteTo(w)
+}
+
+// writeHunk serializes one hunk and every auxiliary block attached to
+// it, terminated by HUNK_END.
+func writeHunk(wr *writer, h *Hunk) error {
+	if h.Name != "" {
+		wr.long(HunkName)
+		wr.string(h.Name)
+	}
+
+	switch h.Type {
+	case HunkCode, HunkData:
+		wr.long(h.Type)
+		wr.long(encodeSize(h.MemType, h.Size/LongWordSize))
+		if h.MemType == MemAdditional {
+			wr.long(h.AddMemFlags)
+		}
+		data := make([]byte, h.Size)
+		if h.Size > 0 {
+			if _, err := io.ReadFull(h.Open(), data); err != nil {
+				return err
+			}
+		}
+		wr.bytes(data)
+	case HunkBSS:
+		wr.long(h.Type)
+		wr.long(encodeSize(h.MemType, h.Size/LongWordSize))
+		if h.MemType == MemAdditional {
+			wr.long(h.AddMemFlags)
+		}
+	}
+
+	writeRelocs(wr, h.Relocs)
+
+	if len(h.Externals) > 0 {
+		wr.long(HunkExt)
+		for _, e := range h.Externals {
+			wr.long(uint32(e.Kind)<<24 | uint32(len(padString(e.Name))/LongWordSize))
+			wr.bytes(padString(e.Name))
+			switch e.Kind {
+			case ExtDef, ExtAbs, ExtRes:
+				wr.long(e.Value)
+			case ExtRef32, ExtRef16, ExtRef8:
+				wr.long(uint32(len(e.Refs)))
+				for _, ref := range e.Refs {
+					wr.long(ref)
+				}
+			case ExtCommon:
+				wr.long(e.Value)
+				wr.long(uint32(len(e.Refs)))
+				for _, ref := range e.Refs {
+					wr.long(ref)
+				}
+			}
+		}
+		wr.long(0)
+	}
+
+	if len(h.Symbols) > 0 {
+		wr.long(HunkSymbol)
+		for _, s := range h.Symbols {
+			name := padString(s.Name)
+			wr.long(uint32(len(name) / LongWordSize))
+			wr.bytes(name)
+			wr.long(s.Value)
+		}
+		wr.long(0)
+	}
+
+	for _, d := range h.Debug {
+		wr.long(HunkDebug)
+		var payload []byte
+		if d.Kind != "" {
+			payload = append([]byte(d.Kind), d.Data...)
+		} else {
+			payload = append([]byte(nil), d.Data...)
+		}
+		for len(payload)%LongWordSize != 0 {
+			payload = append(payload, 0)
+		}
+		wr.long(uint32(LongWordSize+len(payload)) / LongWordSize)
+		wr.long(d.Offset)
+		wr.bytes(payload)
+	}
+
+	if h.Overlay != nil {
+		wr.long(HunkOverlay)
+		wr.long(uint32(len(h.Overlay)) / LongWordSize)
+		wr.bytes(h.Overlay)
+	}
+
+	if h.HasBreak {
+		wr.long(HunkBreak)
+	}
+
+	wr.long(HunkEnd)
+	return nil
+}
+
+// relocBlockType returns the block type identifier for a group of
+// relocations sharing the given width and delta-ness.
+func relocBlockType(width int, delta bool) uint32 {
+	switch {
+	case width == 32 && !delta:
+		return HunkReloc32
+	case width == 32 && delta:
+		return HunkDrel32
+	case width == 16 && !delta:
+		return HunkReloc16
+	case width == 16 && delta:
+		return HunkDrel16
+	case width == 8 && !delta:
+		return HunkReloc8
+	default:
+		return HunkDrel8
+	}
+}
+
+// writeRelocs emits one relocation block per distinct (width, delta)
+// combination present in relocs, each holding every group of that
+// kind, terminated as readReloc expects.
+func writeRelocs(wr *writer, relocs []Reloc) {
+	order := []struct {
+		width int
+		delta bool
+	}{{32, false}, {32, true}, {16, false}, {16, true}, {8, false}, {8, true}}
+
+	for _, kind := range order {
+		var group []Reloc
+		for _, r := range relocs {
+			if r.Width == kind.width && r.Delta == kind.delta {
+				group = append(group, r)
+			}
+		}
+		if len(group) == 0 {
+			continue
+		}
+
+		wr.long(relocBlockType(kind.width, kind.delta))
+		if kind.width == 32 {
+			for _, r := range group {
+				wr.long(uint32(len(r.Offsets)))
+				wr.long(uint32(r.HunkIndex))
+				for _, offset := range r.Offsets {
+					wr.long(offset)
+				}
+			}
+			wr.long(0)
+			continue
+		}
+
+		for _, r := range group {
+			wr.word(uint16(len(r.Offsets)))
+			wr.word(uint16(r.HunkIndex))
+			for _, offset := range r.Offsets {
+				if kind.width == 16 {
+					wr.word(uint16(offset))
+				} else {
+					wr.byteVal(byte(offset))
+				}
+			}
+		}
+		wr.word(0)
+		wr.align()
+	}
+}
+
+// padString pads s with trailing NUL bytes to a long word boundary,
+// the form HUNK_NAME/HUNK_EXT/HUNK_SYMBOL strings are stored in.
+func padString(s string) []byte {
+	b := []byte(s)
+	for len(b)%LongWordSize != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
@@ -0,0 +1,25 @@
+package hunk
+
+import "testing"
+
+// TestWriteHunkDoesNotAliasDebugData guards against writeHunk padding
+// Debug.Data in place: if Data aliases a larger backing array (as
+// FromJSON-decoded or otherwise shared data might), padding it must
+// not clobber bytes beyond the slice that belong to the caller.
+func TestWriteHunkDoesNotAliasDebugData(t *testing.T) {
+	backing := make([]byte, 10)
+	for i := range backing {
+		backing[i] = 0xAA
+	}
+	data := backing[:5] // length not a multiple of LongWordSize, so padding kicks in
+
+	var wr writer
+	h := &Hunk{Debug: []Debug{{Offset: 0, Data: data}}}
+	if err := writeHunk(&wr, h); err != nil {
+		t.Fatalf("writeHunk: %v", err)
+	}
+
+	if backing[5] != 0xAA {
+		t.Errorf("writeHunk clobbered caller's backing array at index 5: got %#x, want 0xaa", backing[5])
+	}
+}
@@ -0,0 +1,180 @@
+package hunk
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Stab entry types relevant to resolving an address to a source
+// location, as emitted by gcc -gstabs. See the a.out stab(5) format.
+const (
+	stabSO    = 0x64 // compilation unit: start of source file
+	stabSOL   = 0x84 // name of an included source file
+	stabFUN   = 0x24 // function: value is the function's base address
+	stabSLINE = 0x44 // source line: value is the offset within the function
+)
+
+// stabEntrySize is the size in bytes of a single a.out-style stab
+// entry: n_strx, n_type, n_other, n_desc, n_value.
+const stabEntrySize = 12
+
+// stabEntry is a single gcc stabs debugging entry.
+type stabEntry struct {
+	strx  uint32
+	typ   byte
+	other byte
+	desc  uint16
+	value uint32
+}
+
+// parseStabs splits the payload of a HUNK_DEBUG block holding gcc
+// stabs data into its stab entry table and its out-of-line string
+// table. The payload begins with two long words giving the size in
+// bytes of each, as written by the AmigaOS gcc backend.
+func parseStabs(data []byte) ([]stabEntry, []byte, error) {
+	if len(data) < 8 {
+		return nil, nil, fmt.Errorf("hunk: stab data too short")
+	}
+	stabSize := binary.BigEndian.Uint32(data[0:4])
+	strSize := binary.BigEndian.Uint32(data[4:8])
+	body := data[8:]
+	if uint64(stabSize)+uint64(strSize) > uint64(len(body)) {
+		return nil, nil, fmt.Errorf("hunk: truncated stab data")
+	}
+
+	stabBytes := body[:stabSize]
+	strTab := body[stabSize : stabSize+strSize]
+
+	var entries []stabEntry
+	for i := 0; i+stabEntrySize <= len(stabBytes); i += stabEntrySize {
+		entries = append(entries, stabEntry{
+			strx:  binary.BigEndian.Uint32(stabBytes[i : i+4]),
+			typ:   stabBytes[i+4],
+			other: stabBytes[i+5],
+			desc:  binary.BigEndian.Uint16(stabBytes[i+6 : i+8]),
+			value: binary.BigEndian.Uint32(stabBytes[i+8 : i+12]),
+		})
+	}
+	return entries, strTab, nil
+}
+
+// stabString returns the NUL-terminated string starting at strx in
+// strTab.
+func stabString(strTab []byte, strx uint32) string {
+	if strx >= uint32(len(strTab)) {
+		return ""
+	}
+	end := strx
+	for end < uint32(len(strTab)) && strTab[end] != 0 {
+		end++
+	}
+	return string(strTab[strx:end])
+}
+
+// stabStringAt returns the (possibly continued) string of the stab
+// entry at i, along with the number of extra entries it consumed. A
+// stab string ending in a trailing backslash is continued by the
+// following entries' strings, as gdb's stabs reader expects.
+func stabStringAt(entries []stabEntry, strTab []byte, i int) (string, int) {
+	s := stabString(strTab, entries[i].strx)
+	consumed := 0
+	for strings.HasSuffix(s, `\`) && i+consumed+1 < len(entries) {
+		consumed++
+		s = strings.TrimSuffix(s, `\`) + stabString(strTab, entries[i+consumed].strx)
+	}
+	return s, consumed
+}
+
+// funName strips the trailing ":descriptor" gcc appends to an N_FUN
+// stab string (e.g. "main:F1" for a global function returning int),
+// leaving just the function's name.
+func funName(s string) string {
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// LineInfo is the result of resolving a code offset to a source
+// location via (*File).ResolveOffset.
+type LineInfo struct {
+	File     string
+	Line     int
+	Func     string
+	FuncBase uint32
+	Offset   uint32
+}
+
+// String formats a LineInfo the way GccFindHit reports an Enforcer or
+// MuForce hit: "file:line function+delta".
+func (info LineInfo) String() string {
+	return fmt.Sprintf("%s:%d %s+%#x", info.File, info.Line, info.Func, info.Offset-info.FuncBase)
+}
+
+// ResolveOffset finds the source file, line and enclosing function
+// that correspond to offset off within hunk number hunkIndex, by
+// walking the gcc stabs debug info attached to that hunk the same way
+// the classic GccFindHit utility does: N_SO/N_SOL track the current
+// source file, N_FUN brackets a function and gives its base address,
+// and for each N_SLINE the greatest funcBase+value not exceeding off
+// is taken as the best match.
+func (f *File) ResolveOffset(hunkIndex int, off uint32) (LineInfo, error) {
+	if hunkIndex < 0 || hunkIndex >= len(f.Hunks) {
+		return LineInfo{}, fmt.Errorf("hunk: hunk index %d out of range", hunkIndex)
+	}
+	h := f.Hunks[hunkIndex]
+	if off >= h.Size {
+		return LineInfo{}, fmt.Errorf("hunk: offset %#x out of range for hunk %d (size %#x)", off, hunkIndex, h.Size)
+	}
+
+	var best LineInfo
+	var bestAddr uint32
+	found := false
+
+	for _, d := range h.Debug {
+		if d.Kind != "" {
+			continue // LINE/HCLN tables are not stabs and aren't walked here
+		}
+
+		entries, strTab, err := parseStabs(d.Data)
+		if err != nil {
+			return LineInfo{}, err
+		}
+
+		var currentFile, currentFun string
+		var currentFunBase uint32
+
+		for i := 0; i < len(entries); i++ {
+			e := entries[i]
+			name, extra := stabStringAt(entries, strTab, i)
+			i += extra
+
+			switch e.typ {
+			case stabSO, stabSOL:
+				currentFile = name
+			case stabFUN:
+				currentFun = funName(name)
+				currentFunBase = e.value
+			case stabSLINE:
+				addr := currentFunBase + e.value
+				if addr <= off && (!found || addr > bestAddr) {
+					best = LineInfo{
+						File:     currentFile,
+						Line:     int(e.desc),
+						Func:     currentFun,
+						FuncBase: currentFunBase,
+						Offset:   off,
+					}
+					bestAddr = addr
+					found = true
+				}
+			}
+		}
+	}
+
+	if !found {
+		return LineInfo{}, fmt.Errorf("hunk: no debug information for offset %#x in hunk %d", off, hunkIndex)
+	}
+	return best, nil
+}
@@ -0,0 +1,107 @@
+package hunk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// rawStab builds the payload of a HUNK_DEBUG block carrying stabs
+// data: the stabSize/strSize header, the given entries, and a string
+// table built by concatenating strs (in order, NUL-separated). It
+// returns the payload along with the byte offset of each of strs
+// within the table, for entries to reference via strx.
+func rawStab(t *testing.T, entries []stabEntry, strs []string) ([]byte, []uint32) {
+	t.Helper()
+
+	var strTab bytes.Buffer
+	offsets := make([]uint32, len(strs))
+	for i, s := range strs {
+		offsets[i] = uint32(strTab.Len())
+		strTab.WriteString(s)
+		strTab.WriteByte(0)
+	}
+
+	var entBuf bytes.Buffer
+	for _, e := range entries {
+		binary.Write(&entBuf, binary.BigEndian, e.strx)
+		entBuf.WriteByte(e.typ)
+		entBuf.WriteByte(e.other)
+		binary.Write(&entBuf, binary.BigEndian, e.desc)
+		binary.Write(&entBuf, binary.BigEndian, e.value)
+	}
+
+	var out bytes.Buffer
+	binary.Write(&out, binary.BigEndian, uint32(entBuf.Len()))
+	binary.Write(&out, binary.BigEndian, uint32(strTab.Len()))
+	out.Write(entBuf.Bytes())
+	out.Write(strTab.Bytes())
+	return out.Bytes(), offsets
+}
+
+func TestResolveOffset(t *testing.T) {
+	// main.c: function "main" (descriptor "F1") starts at offset 0 and
+	// has a source line at offset 4.
+	_, offsets := rawStab(t, nil, []string{"main.c", "main:F1"})
+	entries := []stabEntry{
+		{strx: offsets[0], typ: stabSO},
+		{strx: offsets[1], typ: stabFUN, value: 0},
+		{strx: offsets[0], typ: stabSLINE, desc: 42, value: 4},
+	}
+	data, _ := rawStab(t, entries, []string{"main.c", "main:F1"})
+
+	f := &File{Hunks: []*Hunk{
+		{Size: 0x20, Debug: []Debug{{Data: data}}},
+	}}
+
+	info, err := f.ResolveOffset(0, 4)
+	if err != nil {
+		t.Fatalf("ResolveOffset(0, 4): %v", err)
+	}
+	if info.Func != "main" {
+		t.Errorf("Func = %q, want %q (descriptor suffix should be stripped)", info.Func, "main")
+	}
+	if info.Line != 42 {
+		t.Errorf("Line = %d, want 42", info.Line)
+	}
+	if got, want := info.String(), "main.c:42 main+0x4"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	// An offset past the end of the hunk must not resolve against a
+	// different hunk's debug info, regardless of how coarse its own
+	// N_SLINE coverage is.
+	if _, err := f.ResolveOffset(0, 0x1800); err == nil {
+		t.Errorf("ResolveOffset(0, 0x1800) on a 0x20-byte hunk should fail, got a result")
+	}
+}
+
+func TestFunName(t *testing.T) {
+	cases := map[string]string{
+		"main:F1": "main",
+		"foo:f1":  "foo",
+		"plain":   "plain",
+		"":        "",
+	}
+	for in, want := range cases {
+		if got := funName(in); got != want {
+			t.Errorf("funName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestStabStringContinuation(t *testing.T) {
+	entries := []stabEntry{{strx: 0, typ: stabSO}, {strx: 3, typ: stabSO}}
+	strTab := []byte("a\\\x00b\x00")
+	s, consumed := stabStringAt(entries, strTab, 0)
+	if consumed != 1 {
+		t.Fatalf("consumed = %d, want 1", consumed)
+	}
+	if s != "ab" {
+		t.Fatalf("s = %q, want %q", s, "ab")
+	}
+	if !strings.HasPrefix(s, "a") {
+		t.Fatalf("s = %q", s)
+	}
+}
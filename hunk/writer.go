@@ -0,0 +1,52 @@
+package hunk
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// writer accumulates the serialized form of a Hunk file, the mirror
+// image of reader.
+type writer struct {
+	buf bytes.Buffer
+}
+
+func (w *writer) long(v uint32) {
+	var b [LongWordSize]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	w.buf.Write(b[:])
+}
+
+func (w *writer) word(v uint16) {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	w.buf.Write(b[:])
+}
+
+func (w *writer) byteVal(v byte) {
+	w.buf.WriteByte(v)
+}
+
+func (w *writer) bytes(b []byte) {
+	w.buf.Write(b)
+}
+
+// align pads the buffer with NUL bytes up to the next long word
+// boundary.
+func (w *writer) align() {
+	for w.buf.Len()%LongWordSize != 0 {
+		w.buf.WriteByte(0)
+	}
+}
+
+// string writes a Hunk-style length-prefixed string: a long word
+// holding the length in long words, followed by the text padded with
+// NUL bytes to a long word boundary.
+func (w *writer) string(s string) {
+	padded := []byte(s)
+	for len(padded)%LongWordSize != 0 {
+		padded = append(padded, 0)
+	}
+	w.long(uint32(len(padded) / LongWordSize))
+	w.bytes(padded)
+}
@@ -1,234 +1,260 @@
+// Command amginspect prints information about AmigaOS Hunk files,
+// especially executables. The format is described in "The AmigaDOS
+// Manual", Chapter 10.
 package main
 
-//
-// A tool to get information from AmigaOS Hunk files, especially executables.
-// The format is described in "The AmigaDOS Manual", Chapter 10
-//
-
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+
+	"github.com/tautologico/amginspect/hunk"
 )
 
-// constants
+// longWordsPerLine determines the number of long words to show in each
+// line of a raw code or data dump.
+var longWordsPerLine = 4
 
-const LONGWORD_SIZE = 4
+// disasm enables disassembly of HunkCode blocks in printHunk, in place
+// of the raw long word dump. Set by the --disasm flag in dump.
+var disasm bool
 
-const (
-	HunkUnit    = 0x000003E7
-	HunkName    = 0x000003E8
-	HunkCode    = 0x000003E9
-	HunkData    = 0x000003EA
-	HunkBSS     = 0x000003EB
-	HunkReloc32 = 0x000003EC
-	HunkReloc16 = 0x000003ED
-	HunkEnd     = 0x000003F2
-)
+func main() {
+	// The json format is meant to be piped to other tools, so it's kept
+	// free of the banner and progress messages the text dump prints.
+	if !wantsJSON(os.Args[1:]) {
+		fmt.Println("Amiga Inspect")
+	}
 
-var hunkTypeMap = map[uint32]string{
-	HunkUnit:    "Start of program unit",
-	HunkName:    "Name block",
-	HunkCode:    "Code block",
-	HunkData:    "Initialized data block",
-	HunkBSS:     "Uninitialized data block",
-	HunkReloc32: "32-bit relocation information",
-	HunkReloc16: "16-bit relocation information",
-	HunkEnd:     "End block of a hunk",
-}
+	if len(os.Args) == 1 {
+		fmt.Printf("usage: amginspect [--disasm] <file> | amginspect findhit <file> <hex-offset>... | amginspect convert ...")
+		os.Exit(0)
+	}
 
-// longWordsPerLine determine the number of long words to show in each line of
-// a raw code or data dump
-var longWordsPerLine = 4
+	switch os.Args[1] {
+	case "findhit":
+		findHit(os.Args[2:])
+		return
+	case "convert":
+		convert(os.Args[2:])
+		return
+	}
 
-// longWordSlice returns a slice containing the next long word
-// in the stream at offset.
-func longWordSlice(stream []byte, offset uint) []byte {
-	return stream[offset : offset+LONGWORD_SIZE]
+	dump(os.Args[1:])
 }
 
-// longWordValue returns the next long word in the stream
-// at offset as an unsigned 32-bit value, assuming Big Endian
-// byte ordering.
-func longWordValue(stream []byte, offset uint) uint32 {
-	slice := longWordSlice(stream, offset)
-	return uint32(slice[3]) + uint32(slice[2])<<8 +
-		uint32(slice[1])<<16 + uint32(slice[0])<<24
+// wantsJSON reports whether args requests the json output format,
+// without fully parsing flags (main needs this before deciding
+// whether to print its startup banner).
+func wantsJSON(args []string) bool {
+	for _, a := range args {
+		if a == "--format=json" || a == "-format=json" {
+			return true
+		}
+	}
+	return false
 }
 
-// Buffer keeps a byte stream and current position in the stream.
-type Buffer struct {
-	stream []byte
-	offset uint
-}
+// dump implements the default "amginspect [--disasm] <file>"
+// subcommand: it opens file and prints a summary of its header
+// followed by a dump of every hunk it contains.
+func dump(args []string) {
+	fs := flag.NewFlagSet("amginspect", flag.ExitOnError)
+	fs.BoolVar(&disasm, "disasm", false, "disassemble HunkCode blocks as M68K instructions")
+	format := fs.String("format", "", "output format: empty for the default text dump, or json")
+	fs.Parse(args)
+
+	rest := fs.Args()
+	if len(rest) != 1 {
+		fmt.Println("usage: amginspect [--disasm] [--format=json] <file>")
+		os.Exit(1)
+	}
 
-func createBuffer(stream []byte) Buffer {
-	var b Buffer
-	b.stream = stream
-	b.offset = 0
-	return b
-}
+	// The json format is meant to be piped to other tools, so it's the
+	// only output written to stdout in that mode.
+	if *format == "json" {
+		f, err := hunk.Open(rest[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
 
-func (b *Buffer) nextLongWord() uint32 {
-	b.offset += LONGWORD_SIZE
-	return longWordValue(b.stream, b.offset-LONGWORD_SIZE)
-}
+		data, err := f.ToJSON()
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Stdout.Write(data)
+		fmt.Println()
+		return
+	}
 
-func (b *Buffer) nextLongWordAsSlice() []byte {
-	b.offset += LONGWORD_SIZE
-	return longWordSlice(b.stream, b.offset-LONGWORD_SIZE)
-}
+	fmt.Printf("Opening file %s ...\n", rest[0])
 
-func (b *Buffer) advancePointer(offset uint) {
-	b.offset += offset
-}
+	f, err := hunk.Open(rest[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
 
-func printLongWordSlice(longWord []byte) string {
-	return fmt.Sprintf("%02x %02x %02x %02x", longWord[0], longWord[1],
-		longWord[2], longWord[3])
+	printFile(f)
 }
 
-func main() {
-	fmt.Println("Amiga Inspect")
-
-	if len(os.Args) == 1 {
-		fmt.Printf("usage: amginspect <file>")
-		os.Exit(0)
+// findHit resolves each of a list of hex code offsets, as reported by
+// Enforcer or MuForce, to a source file, line and function, the same
+// way the classic GccFindHit utility does. It tries every hunk in the
+// file in turn and reports the first one with matching debug info.
+func findHit(args []string) {
+	if len(args) < 2 {
+		fmt.Println("usage: amginspect findhit <file> <hex-offset>...")
+		os.Exit(1)
 	}
 
-	content, err := os.ReadFile(os.Args[1])
+	f, err := hunk.Open(args[0])
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer f.Close()
 
-	fmt.Printf("Opening file %s ...\n", os.Args[1])
+	for _, arg := range args[1:] {
+		off, err := strconv.ParseUint(strings.TrimPrefix(arg, "0x"), 16, 32)
+		if err != nil {
+			fmt.Printf("%s: invalid hex offset: %v\n", arg, err)
+			continue
+		}
 
-	buffer := createBuffer(content)
-	processFile(&buffer)
-}
+		var info hunk.LineInfo
+		resolved := false
+		for i := range f.Hunks {
+			if info, err = f.ResolveOffset(i, uint32(off)); err == nil {
+				resolved = true
+				break
+			}
+		}
 
-func processFile(buffer *Buffer) {
-	if !checkHunkHeader(buffer) {
-		fmt.Println("Incorrect header for Amiga Executable")
-		return
+		if !resolved {
+			fmt.Printf("%#x: no debug information found\n", off)
+			continue
+		}
+		fmt.Printf("%#x: %s\n", off, info)
 	}
+}
+
+// printFile prints a summary of f's header followed by a dump of
+// every hunk it contains.
+func printFile(f *hunk.File) {
 	fmt.Println("* Header check OK")
-	residentLibraries(buffer)
-	hTableSize := hunkTableSize(buffer)
-	fmt.Printf("* Hunk table size: %d\n", hTableSize)
-	firstHunk := firstHunkNumber(buffer)
-	fmt.Printf("* First hunk: %d\n", firstHunk)
-	lastHunk := lastHunkNumber(buffer)
-	fmt.Printf("* Last hunk: %d\n", lastHunk)
-	totalHunks := lastHunk - firstHunk + 1
-	fmt.Printf("* Total number of hunks in file: %d\n", totalHunks)
-	hSizes := hunkSizes(buffer, totalHunks)
-	for i, hSize := range hSizes {
-		fmt.Printf("* Memory size for hunk %d: %d\n", i, hSize)
+	fmt.Println("* No calls to resident libraries found")
+	fmt.Printf("* First hunk: %d\n", f.FirstHunk)
+	fmt.Printf("* Last hunk: %d\n", f.LastHunk)
+	fmt.Printf("* Total number of hunks in file: %d\n", len(f.Hunks))
+	for i, size := range f.HunkSizes {
+		fmt.Printf("* Memory size for hunk %d: %d\n", i, size)
 	}
 
 	fmt.Printf("========================================\n")
-	for i := 0; i < int(totalHunks); i++ {
+	for i, h := range f.Hunks {
 		fmt.Printf("* Dumping Hunk #%d\n", i)
 		fmt.Printf("----------\n")
-		for !dumpHunkBlock(buffer) {
-			fmt.Printf("----------\n")
-		}
+		printHunk(h)
 		fmt.Printf("========================================\n")
 	}
 }
 
-// checkHunkHeader checks if the byte stream begins with the
-// AmigaOS "magic cookie" for Hunk executable files (0x000003f3).
-func checkHunkHeader(b *Buffer) bool {
-	header := b.nextLongWordAsSlice()
-	return (header[0] == 0x00) && (header[1] == 0x00) &&
-		(header[2] == 0x03) && (header[3] == 0xf3)
-}
+// printHunk prints the type and size of a single hunk (and, for a code
+// hunk, its contents), followed by every auxiliary block (relocations,
+// externals, symbols, debug info) that applies to it.
+func printHunk(h *hunk.Hunk) {
+	fmt.Printf("* Hunk block type: %s\n", hunk.TypeName(h.Type))
+	if h.Name != "" {
+		fmt.Printf("* Hunk name: %s\n", h.Name)
+	}
 
-// residentLibraries scans the list of names of resident libraries
-// that should be loaded with the program, and returns the offset
-// to the first byte after the table.
-// TODO: right now it assumes there are no resident libraries in the list
-func residentLibraries(buffer *Buffer) {
-	if buffer.nextLongWord() != 0 {
-		fmt.Println("Calls to resident libraries found")
-		os.Exit(0)
+	switch h.Type {
+	case hunk.HunkCode:
+		fmt.Printf("* Code block size: %d long words = %d bytes (%s memory)\n",
+			h.Size/hunk.LongWordSize, h.Size, h.MemType)
+		if disasm {
+			fmt.Printf("** Disassembly: \n")
+			printDisasm(h)
+		} else {
+			fmt.Printf("** Code: \n")
+			printLongWords(h)
+		}
+	case hunk.HunkData:
+		fmt.Printf("** Data block size: %d long words = %d bytes (%s memory)\n",
+			h.Size/hunk.LongWordSize, h.Size, h.MemType)
+	case hunk.HunkBSS:
+		fmt.Printf("** BSS block size: %d long words = %d bytes (%s memory)\n",
+			h.Size/hunk.LongWordSize, h.Size, h.MemType)
 	}
-	fmt.Println("* No calls to resident libraries found")
-}
 
-// hunkTableSize reads and returns the Hunk table size needed by a
-// loader when loading the program. This includes the hunks included
-// in the file but also hunks loaded from resident libraries.
-// The second return value is the offset of the next field in the format.
-func hunkTableSize(buffer *Buffer) uint32 {
-	return buffer.nextLongWord()
-}
+	for i, r := range h.Relocs {
+		kind := "reloc"
+		if r.Delta {
+			kind = "drel"
+		}
+		fmt.Printf("** %s%d (%d-bit) targeting hunk %d:\n", kind, i+1, r.Width, r.HunkIndex)
+		for j, offset := range r.Offsets {
+			fmt.Printf("** Offset %d: %d\n", j, offset)
+		}
+	}
 
-// firstHunkNumber retrieves the number of the first hunk in the hunk
-// table that should be loaded. If no resident libraries are referenced,
-// this should always be zero.
-func firstHunkNumber(buffer *Buffer) uint32 {
-	return buffer.nextLongWord()
-}
+	for _, e := range h.Externals {
+		fmt.Printf("** External %q (kind %d): value=%d refs=%v\n", e.Name, e.Kind, e.Value, e.Refs)
+	}
 
-// lastHunkNumber retrieves the number of the last hunk in the hunk
-// table that should be loaded.
-func lastHunkNumber(buffer *Buffer) uint32 {
-	return buffer.nextLongWord()
-}
+	for _, s := range h.Symbols {
+		fmt.Printf("** Symbol %q: %#x\n", s.Name, s.Value)
+	}
 
-// hunkSizes retrieves the sizes of hunks in the hunk table.
-func hunkSizes(buffer *Buffer, hunks uint32) []uint32 {
-	var result []uint32
+	for _, d := range h.Debug {
+		if d.Kind != "" {
+			fmt.Printf("** Debug info (%s) at offset %d, %d bytes\n", d.Kind, d.Offset, len(d.Data))
+		} else {
+			fmt.Printf("** Debug info (stabs) at offset %d, %d bytes\n", d.Offset, len(d.Data))
+		}
+	}
 
-	for i := 0; i < int(hunks); i++ {
-		result = append(result, buffer.nextLongWord())
+	if h.Overlay != nil {
+		fmt.Printf("** Overlay block: %d bytes\n", len(h.Overlay))
+	}
+	if h.HasBreak {
+		fmt.Printf("** Overlay break\n")
 	}
-	return result
 }
 
-// dumpHunkBlock displays information about the hunk block
-// starting at the current buffer pointer (and advances
-// the pointer to the next block). Returns true if
-// this is the last block in the hunk.
-func dumpHunkBlock(buffer *Buffer) bool {
-	hunkType := buffer.nextLongWord()
-	fmt.Printf("* Hunk block type: %s\n", showHunkType(hunkType))
-
-	switch hunkType {
-	case HunkCode:
-		dumpCodeBlock(buffer)
-	case HunkReloc32:
-		dumpReloc32Block(buffer)
-	case HunkData:
-		dumpDataBlock(buffer)
-	case HunkBSS:
-		dumpBSSBlock(buffer)
+// printDisasm disassembles a code hunk as M68K instructions, one per
+// line, annotating branch targets with symbol names and relocated
+// operands with the hunk+offset they point at.
+func printDisasm(h *hunk.Hunk) {
+	data := make([]byte, h.Size)
+	if h.Size > 0 {
+		if _, err := h.Open().Read(data); err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	return hunkType == HunkEnd
+	for _, in := range hunk.Disasm(data, 0, h.Relocs, h.Symbols) {
+		fmt.Printf("%s\n", in)
+	}
 }
 
-func showHunkType(hunkType uint32) string {
-	typeStr, ok := hunkTypeMap[hunkType]
-	if !ok {
-		return "Unknown hunk block type"
+// printLongWords prints the raw bytes of a code hunk as hex long
+// words, longWordsPerLine to a line.
+func printLongWords(h *hunk.Hunk) {
+	data := make([]byte, h.Size)
+	if _, err := h.Open().Read(data); err != nil {
+		log.Fatal(err)
 	}
-	return typeStr
-}
 
-func dumpCodeBlock(buffer *Buffer) {
-	hunkSize := buffer.nextLongWord()
-	fmt.Printf("* Code block size: %d long words = %d bytes\n", hunkSize,
-		hunkSize*LONGWORD_SIZE)
-	fmt.Printf("** Code: \n")
 	lwNumber := 0
-	for i := 0; i < int(hunkSize); i++ {
-		nextLW := buffer.nextLongWordAsSlice()
-		fmt.Printf("%s ", printLongWordSlice(nextLW))
-		lwNumber += 1
+	for offset := 0; offset < len(data); offset += hunk.LongWordSize {
+		lw := data[offset : offset+hunk.LongWordSize]
+		fmt.Printf("%02x %02x %02x %02x ", lw[0], lw[1], lw[2], lw[3])
+		lwNumber++
 		if lwNumber == longWordsPerLine {
 			fmt.Printf("\n")
 			lwNumber = 0
@@ -236,30 +262,3 @@ func dumpCodeBlock(buffer *Buffer) {
 	}
 	fmt.Printf("\n")
 }
-
-func dumpReloc32Block(buffer *Buffer) {
-	i := 0
-	n := buffer.nextLongWord()
-	for n != 0 {
-		fmt.Printf("** N%d: %d\n", i+1, n)
-		fmt.Printf("** Hunk number %d: %d\n", i+1, buffer.nextLongWord())
-		for offs := 0; offs < int(n); offs++ {
-			fmt.Printf("** Offset %d: %s\n", offs, printLongWordSlice(buffer.nextLongWordAsSlice()))
-		}
-		i++
-		n = buffer.nextLongWord()
-	}
-}
-
-func dumpDataBlock(buffer *Buffer) {
-	blockSize := buffer.nextLongWord()
-	fmt.Printf("** Data block size: %d long words = %d bytes\n", blockSize,
-		blockSize*LONGWORD_SIZE)
-	buffer.advancePointer(uint(blockSize * LONGWORD_SIZE))
-}
-
-func dumpBSSBlock(buffer *Buffer) {
-	blockSize := buffer.nextLongWord()
-	fmt.Printf("** BSS block size: %d long words = %d bytes\n", blockSize,
-		blockSize*LONGWORD_SIZE)
-}